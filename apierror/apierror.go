@@ -0,0 +1,165 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/bparsons094/go-server-base/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+var logger = logging.For("apierror")
+
+// Error is an error a handler returns to have Handler render it. Message is
+// shown to the client, so it must not carry internal details.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Fields  []FieldError
+	// Cause is logged but never sent to the client
+	Cause error
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Envelope is the body of every error response:
+//
+//	{
+//	  "status": "fail",              // "fail" for 4xx, "error" for 5xx
+//	  "code": "validation_failed",   // stable, machine readable
+//	  "message": "Request validation failed",
+//	  "errors": [{"field": "subAccountId", "rule": "uuid", "message": "must be a valid UUID"}],
+//	  "requestId": "0b8f..."
+//	}
+//
+// errors is only present for validation failures.
+type Envelope struct {
+	Status    string       `json:"status"`
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+}
+
+func New(status int, code string, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func BadRequest(message string) *Error {
+	return New(fiber.StatusBadRequest, "bad_request", message)
+}
+
+func Unauthorized(message string) *Error {
+	return New(fiber.StatusUnauthorized, "unauthorized", message)
+}
+
+func Forbidden(message string) *Error {
+	return New(fiber.StatusForbidden, "forbidden", message)
+}
+
+func NotFound(message string) *Error {
+	return New(fiber.StatusNotFound, "not_found", message)
+}
+
+func Validation(fields []FieldError) *Error {
+	return &Error{Status: fiber.StatusUnprocessableEntity, Code: "validation_failed", Message: "Request validation failed", Fields: fields}
+}
+
+func Internal(cause error) *Error {
+	return &Error{Status: fiber.StatusInternalServerError, Code: "internal_error", Message: "Internal Server Error", Cause: cause}
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Status returns the HTTP status an error is rendered with
+func Status(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Status
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// From converts any error into an *Error. Fiber's own errors, such as 404s and
+// 405s from the router, keep their status and message. Anything else is an
+// internal error.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return New(fiberErr.Code, codeFor(fiberErr.Code), fiberErr.Message)
+	}
+	return Internal(err)
+}
+
+// Handler is the fiber ErrorHandler. Panics reach it through the recover
+// middleware as plain errors, so they render as internal errors.
+func Handler(c *fiber.Ctx, err error) error {
+	apiErr := From(err)
+
+	ctx := logging.Context(c)
+	if apiErr.Status >= fiber.StatusInternalServerError {
+		logger.ErrorContext(ctx, "Request failed", "method", c.Method(), "path", c.Path(), "status", apiErr.Status, "error", err)
+	}
+
+	status := "fail"
+	if apiErr.Status >= fiber.StatusInternalServerError {
+		status = "error"
+	}
+
+	return c.Status(apiErr.Status).JSON(Envelope{
+		Status:    status,
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Errors:    apiErr.Fields,
+		RequestID: utils.GetRequestID(c.UserContext()),
+	})
+}
+
+// codeFor derives a code from a status for errors that don't carry one, e.g.
+// 405 becomes "method_not_allowed"
+func codeFor(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+
+	code := make([]byte, 0, len(text))
+	for _, r := range []byte(text) {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			code = append(code, r+'a'-'A')
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			code = append(code, r)
+		default:
+			if len(code) > 0 && code[len(code)-1] != '_' {
+				code = append(code, '_')
+			}
+		}
+	}
+	return string(code)
+}
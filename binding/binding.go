@@ -0,0 +1,187 @@
+package binding
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+)
+
+var validate = newValidator()
+
+// Bind parses the request into a T and validates it with its validate tags.
+// The body is read with json or form tags, depending on its content type,
+// then query values with query tags and path parameters with params tags, so
+// the path wins when a field is set twice. Failures come back as an
+// *apierror.Error, ready to be returned from the handler.
+func Bind[T any](c *fiber.Ctx) (T, error) {
+	var data T
+
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&data); err != nil {
+			if errors.Is(err, fiber.ErrUnprocessableEntity) {
+				return data, apierror.New(fiber.StatusUnsupportedMediaType, "unsupported_media_type", "Unsupported content type")
+			}
+			return data, apierror.BadRequest("Failed parsing request body")
+		}
+	}
+
+	if err := c.QueryParser(&data); err != nil {
+		return data, apierror.BadRequest("Failed parsing query parameters")
+	}
+
+	if err := c.ParamsParser(&data); err != nil {
+		return data, apierror.BadRequest("Failed parsing path parameters")
+	}
+
+	if err := Validate(data); err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// Validate runs a struct's validate tags, returning an *apierror.Error with one
+// FieldError per failed rule
+func Validate(data any) error {
+	err := validate.Struct(data)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		// Only returned for programming errors, like validating a non-struct
+		return apierror.Internal(err)
+	}
+
+	fields := make([]apierror.FieldError, len(validationErrors))
+	for i, fieldErr := range validationErrors {
+		fields[i] = apierror.FieldError{
+			Field:   fieldPath(reflect.TypeOf(data), fieldErr),
+			Rule:    fieldErr.Tag(),
+			Message: fieldMessage(fieldErr),
+		}
+	}
+	return apierror.Validation(fields)
+}
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by the name the client sent them under
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "query", "params", "form"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				continue
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+
+	return v
+}
+
+// fieldPath drops the struct's own name and any embedded structs from the
+// namespace, so nested fields read as "address.city" and promoted fields as
+// the client sent them
+func fieldPath(t reflect.Type, fieldErr validator.FieldError) string {
+	names := strings.Split(fieldErr.Namespace(), ".")
+	structNames := strings.Split(fieldErr.StructNamespace(), ".")
+	if len(names) < 2 || len(names) != len(structNames) {
+		return fieldErr.Field()
+	}
+
+	var path []string
+	for i, structName := range structNames[1:] {
+		t = elemType(t)
+		if t.Kind() == reflect.Struct {
+			name, _, _ := strings.Cut(structName, "[")
+			if field, ok := t.FieldByName(name); ok {
+				t = field.Type
+				if field.Anonymous {
+					continue
+				}
+			}
+		}
+		path = append(path, names[i+1])
+	}
+	return strings.Join(path, ".")
+}
+
+// elemType unwraps pointers and containers down to the type they hold
+func elemType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+func fieldMessage(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+
+	switch fieldErr.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		return "is required"
+	case "uuid", "uuid4", "uuid_rfc4122", "uuid4_rfc4122":
+		return "must be a valid UUID"
+	case "email":
+		return "must be a valid email address"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "min", "gte":
+		if unit := sizeUnit(fieldErr.Kind()); unit != "" {
+			return "must have at least " + param + " " + unit
+		}
+		return "must be at least " + param
+	case "max", "lte":
+		if unit := sizeUnit(fieldErr.Kind()); unit != "" {
+			return "must have at most " + param + " " + unit
+		}
+		return "must be at most " + param
+	case "len":
+		if unit := sizeUnit(fieldErr.Kind()); unit != "" {
+			return "must have exactly " + param + " " + unit
+		}
+		return "must be " + param
+	case "datetime":
+		if param == time.RFC3339 {
+			return "must be an RFC3339 time, e.g. 2006-01-02T15:04:05Z"
+		}
+		return "must be a time formatted as " + param
+	case "gt":
+		return "must be greater than " + param
+	case "lt":
+		return "must be less than " + param
+	}
+
+	if param != "" {
+		return "failed the " + fieldErr.Tag() + "=" + param + " rule"
+	}
+	return "failed the " + fieldErr.Tag() + " rule"
+}
+
+// sizeUnit is what min, max and len count for a kind, or empty for numbers
+func sizeUnit(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return "items"
+	}
+	return ""
+}
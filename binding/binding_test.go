@@ -0,0 +1,56 @@
+package binding
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bparsons094/go-server-base/apierror"
+)
+
+type pageParams struct {
+	Limit int `query:"limit" validate:"max=10"`
+}
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type searchParams struct {
+	pageParams
+	UserID    string    `query:"userId" validate:"omitempty,uuid_rfc4122"`
+	Address   address   `json:"address"`
+	Addresses []address `json:"addresses" validate:"dive"`
+}
+
+func TestValidateFieldPaths(t *testing.T) {
+	err := Validate(searchParams{
+		pageParams: pageParams{Limit: 11},
+		UserID:     "not-a-uuid",
+		Addresses:  []address{{City: "Oslo"}, {}},
+	})
+
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Validate() = %v, want an *apierror.Error", err)
+	}
+
+	var fields []string
+	for _, field := range apiErr.Fields {
+		fields = append(fields, field.Field)
+	}
+	want := []string{"limit", "userId", "address.city", "addresses[1].city"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+}
+
+func TestValidateAcceptsUpperCaseUUIDs(t *testing.T) {
+	err := Validate(searchParams{
+		UserID:  "3F2504E0-4F89-11D3-9A0C-0305E82C3301",
+		Address: address{City: "Oslo"},
+	})
+	if err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+}
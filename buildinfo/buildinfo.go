@@ -0,0 +1,82 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/bparsons094/go-server-base/utils"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/bparsons094/go-server-base/buildinfo.Version=1.4.0 \
+//	  -X github.com/bparsons094/go-server-base/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/bparsons094/go-server-base/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Anything left empty falls back to the VCS stamp Go embeds in the binary.
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"buildTime"`
+	GoVersion string `json:"goVersion"`
+	Dirty     bool   `json:"dirty"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+func Get() Info {
+	once.Do(func() {
+		info = Info{
+			Version:   Version,
+			Commit:    Commit,
+			BuildTime: BuildTime,
+			GoVersion: runtime.Version(),
+		}
+
+		if buildInfo, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range buildInfo.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					if info.Commit == "" {
+						info.Commit = setting.Value
+					}
+				case "vcs.time":
+					if info.BuildTime == "" {
+						info.BuildTime = setting.Value
+					}
+				case "vcs.modified":
+					info.Dirty = setting.Value == "true"
+				}
+			}
+
+			if info.Version == "" && buildInfo.Main.Version != "(devel)" {
+				info.Version = buildInfo.Main.Version
+			}
+		}
+
+		// Older deploys only set VERSION in the config
+		if info.Version == "" {
+			info.Version = utils.GetConfig().Version
+		}
+		if info.Version == "" {
+			info.Version = "dev"
+		}
+	})
+
+	return info
+}
+
+// LogAttrs returns the build info as slog key value pairs
+func (i Info) LogAttrs() []any {
+	return []any{"version", i.Version, "commit", i.Commit, "build_time", i.BuildTime, "go_version", i.GoVersion, "dirty", i.Dirty}
+}
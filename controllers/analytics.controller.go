@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/bparsons094/go-server-base/binding"
+	"github.com/bparsons094/go-server-base/models"
+	"github.com/bparsons094/go-server-base/requestlogs"
+	"github.com/gofiber/fiber/v2"
+)
+
+const maxRollupRows = 5000
+
+// RollupQueryParams are the query parameters getRollups reads
+type RollupQueryParams struct {
+	Granularity string `query:"granularity" validate:"omitempty,oneof=hourly daily" doc:"Bucket size, hourly by default"`
+	From        string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" doc:"Start of the window"`
+	To          string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00" doc:"End of the window"`
+	Limit       int    `query:"limit" validate:"omitempty,min=0,max=5000"`
+}
+
+type RouteRollupQueryParams struct {
+	RollupQueryParams
+	Route string `query:"route" doc:"Route template, e.g. /api/v1/users/getMe"`
+}
+
+type UserRollupQueryParams struct {
+	RollupQueryParams
+	UserID string `query:"userId" validate:"omitempty,uuid_rfc4122"`
+}
+
+type RollupsResponse struct {
+	Status      string                    `json:"status"`
+	Granularity string                    `json:"granularity"`
+	Rollups     []models.RequestLogRollup `json:"rollups"`
+}
+
+func GetRouteAnalytics(c *fiber.Ctx) error {
+	params, err := binding.Bind[RouteRollupQueryParams](c)
+	if err != nil {
+		return err
+	}
+	return getRollups(c, params.RollupQueryParams, models.RollupDimensionRoute, params.Route)
+}
+
+func GetUserAnalytics(c *fiber.Ctx) error {
+	params, err := binding.Bind[UserRollupQueryParams](c)
+	if err != nil {
+		return err
+	}
+	return getRollups(c, params.RollupQueryParams, models.RollupDimensionUser, params.UserID)
+}
+
+func getRollups(c *fiber.Ctx, params RollupQueryParams, dimension string, dimensionValue string) error {
+	if params.Granularity == "" {
+		params.Granularity = requestlogs.Hourly.Name
+	}
+	granularity, err := requestlogs.GranularityByName(params.Granularity)
+	if err != nil {
+		return apierror.BadRequest(err.Error())
+	}
+
+	// Reuse the request log filter parsing for the time window
+	filter, err := requestlogs.ParseFilter(func(key string) string {
+		switch key {
+		case "from":
+			return params.From
+		case "to":
+			return params.To
+		}
+		return ""
+	})
+	if err != nil {
+		return apierror.BadRequest(err.Error())
+	}
+
+	limit := params.Limit
+	if limit == 0 {
+		limit = maxRollupRows
+	}
+
+	rollups, err := requestlogs.QueryRollups(c.UserContext(), DB, granularity, requestlogs.RollupQuery{
+		Dimension:      dimension,
+		DimensionValue: dimensionValue,
+		From:           filter.From,
+		To:             filter.To,
+		Limit:          limit,
+	})
+	if err != nil {
+		return apierror.Internal(fmt.Errorf("querying rollups: %w", err))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(RollupsResponse{Status: "success", Granularity: granularity.Name, Rollups: rollups})
+}
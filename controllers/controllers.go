@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"github.com/bparsons094/go-server-base/binding"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -19,20 +20,15 @@ func getUserId(c *fiber.Ctx) uuid.UUID {
 
 func getSubAccountId(c *fiber.Ctx) (uuid.UUID, error) {
 	type RequestData struct {
-		SubAccountId string `json:"subAccountId"`
+		SubAccountId string `json:"subAccountId" validate:"required,uuid_rfc4122"`
 	}
 
-	var data RequestData
-	if err := c.BodyParser(&data); err != nil {
-		return uuid.Nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "Failed parsing request body"})
-	}
-
-	subAccountId, err := uuid.Parse(data.SubAccountId)
+	data, err := binding.Bind[RequestData](c)
 	if err != nil {
-		return uuid.Nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"status": "error", "message": "Failed parsing sub account id"})
+		return uuid.Nil, err
 	}
 
-	return subAccountId, nil
+	return uuid.Parse(data.SubAccountId)
 }
 
 func stringToUuid(str string) (uuid.UUID, error) {
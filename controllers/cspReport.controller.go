@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/bparsons094/go-server-base/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// Browsers batch a handful of violations per request, anything past these
+	// limits is noise or abuse of an unauthenticated endpoint
+	maxCSPRequestBytes = 64 << 10
+	maxCSPViolations   = 20
+	// The raw report kept with each violation is cut to this
+	maxCSPReportBytes = 8192
+)
+
+// cspViolation covers both the legacy report-uri body, which uses dashed keys
+// under "csp-report", and the Reporting API body, which uses camel case
+type cspViolation struct {
+	DocumentURI             string `json:"document-uri"`
+	DocumentURL             string `json:"documentURL"`
+	EffectiveDirective      string `json:"effective-directive"`
+	EffectiveDirectiveCamel string `json:"effectiveDirective"`
+	ViolatedDirective       string `json:"violated-directive"`
+	BlockedURI              string `json:"blocked-uri"`
+	BlockedURL              string `json:"blockedURL"`
+	SourceFile              string `json:"source-file"`
+	SourceFileCamel         string `json:"sourceFile"`
+	LineNumber              int    `json:"line-number"`
+	LineNumberCamel         int    `json:"lineNumber"`
+	Disposition             string `json:"disposition"`
+}
+
+func CreateCSPReport(c *fiber.Ctx) error {
+	body := c.Body()
+	if len(body) > maxCSPRequestBytes {
+		return apierror.New(fiber.StatusRequestEntityTooLarge, "payload_too_large", "Report is too large")
+	}
+
+	var violations []cspViolation
+	if strings.HasPrefix(string(c.Request().Header.ContentType()), "application/reports+json") {
+		var reports []struct {
+			Type string       `json:"type"`
+			Body cspViolation `json:"body"`
+		}
+		if err := json.Unmarshal(body, &reports); err != nil {
+			return apierror.BadRequest("Failed parsing report")
+		}
+		for _, report := range reports {
+			if report.Type == "csp-violation" {
+				violations = append(violations, report.Body)
+			}
+		}
+	} else {
+		var report struct {
+			Body cspViolation `json:"csp-report"`
+		}
+		if err := json.Unmarshal(body, &report); err != nil {
+			return apierror.BadRequest("Failed parsing report")
+		}
+		violations = append(violations, report.Body)
+	}
+
+	if len(violations) > maxCSPViolations {
+		violations = violations[:maxCSPViolations]
+	}
+
+	raw := string(body)
+	if len(raw) > maxCSPReportBytes {
+		// Cut on a rune boundary, Postgres rejects invalid UTF-8 in text columns
+		cut := maxCSPReportBytes
+		for cut > 0 && !utf8.RuneStart(raw[cut]) {
+			cut--
+		}
+		raw = raw[:cut]
+	}
+
+	if len(violations) == 0 {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	reports := make([]models.CSPReport, len(violations))
+	for i, violation := range violations {
+		reports[i] = models.CSPReport{
+			DocumentURI:        firstNonEmpty(violation.DocumentURI, violation.DocumentURL),
+			EffectiveDirective: firstNonEmpty(violation.EffectiveDirective, violation.EffectiveDirectiveCamel, violation.ViolatedDirective),
+			BlockedURI:         firstNonEmpty(violation.BlockedURI, violation.BlockedURL),
+			SourceFile:         firstNonEmpty(violation.SourceFile, violation.SourceFileCamel),
+			LineNumber:         max(violation.LineNumber, violation.LineNumberCamel),
+			Disposition:        violation.Disposition,
+			ClientIP:           c.IP(),
+			UserAgent:          c.Get(fiber.HeaderUserAgent),
+			Raw:                raw,
+		}
+	}
+
+	if err := DB.WithContext(c.UserContext()).Create(&reports).Error; err != nil {
+		return apierror.Internal(fmt.Errorf("storing CSP report: %w", err))
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"time"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/bparsons094/go-server-base/binding"
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/gofiber/fiber/v2"
+)
+
+func GetGoroutineDump(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	return pprof.Lookup("goroutine").WriteTo(c, 2)
+}
+
+func GetGCStats(c *fiber.Ctx) error {
+	var gcStats debug.GCStats
+	debug.ReadGCStats(&gcStats)
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status": "success",
+		"gc": fiber.Map{
+			"numGC":         gcStats.NumGC,
+			"lastGC":        gcStats.LastGC,
+			"pauseTotal":    gcStats.PauseTotal.String(),
+			"recentPauses":  durationsToStrings(gcStats.Pause, 10),
+			"gcCPUFraction": memStats.GCCPUFraction,
+			"nextGC":        memStats.NextGC,
+		},
+		"memory": fiber.Map{
+			"heapAlloc":    memStats.HeapAlloc,
+			"heapInuse":    memStats.HeapInuse,
+			"heapIdle":     memStats.HeapIdle,
+			"heapReleased": memStats.HeapReleased,
+			"heapObjects":  memStats.HeapObjects,
+			"stackInuse":   memStats.StackInuse,
+			"sys":          memStats.Sys,
+		},
+		"numGoroutine": runtime.NumGoroutine(),
+	})
+}
+
+func GetLogLevels(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "levels": logging.Levels()})
+}
+
+func SetLogLevel(c *fiber.Ctx) error {
+	type RequestData struct {
+		Component string `json:"component"`
+		Level     string `json:"level" validate:"required"`
+	}
+
+	data, err := binding.Bind[RequestData](c)
+	if err != nil {
+		return err
+	}
+
+	level, err := logging.ParseLevel(data.Level)
+	if err != nil {
+		return apierror.BadRequest("Invalid log level, expected debug, info, warn or error")
+	}
+
+	logging.SetLevel(data.Component, level)
+	logger.InfoContext(c.UserContext(), "Log level changed", "component", data.Component, "level", level.String())
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "levels": logging.Levels()})
+}
+
+func ResetLogLevel(c *fiber.Ctx) error {
+	component := c.Params("component")
+	logging.ResetLevel(component)
+	logger.InfoContext(c.UserContext(), "Log level reset", "component", component)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "levels": logging.Levels()})
+}
+
+func durationsToStrings(durations []time.Duration, limit int) []string {
+	if len(durations) > limit {
+		durations = durations[:limit]
+	}
+
+	values := make([]string, len(durations))
+	for i, duration := range durations {
+		values[i] = duration.String()
+	}
+	return values
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/bparsons094/go-server-base/binding"
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/bparsons094/go-server-base/requestlogs"
+	"github.com/bparsons094/go-server-base/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+var logger = logging.For("controllers")
+
+// RequestLogExportQueryParams are the query parameters ExportRequestLogs
+// reads. The filter itself is built by requestlogs.ParseFilter, which the CLI
+// exporter shares.
+type RequestLogExportQueryParams struct {
+	Format     string `query:"format" validate:"omitempty,oneof=ndjson csv" doc:"ndjson by default"`
+	Gzip       bool   `query:"gzip"`
+	UserID     string `query:"userId" validate:"omitempty,uuid_rfc4122"`
+	From       string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To         string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Method     string `query:"method"`
+	Path       string `query:"path" doc:"Path prefix"`
+	Route      string `query:"route" doc:"Route template"`
+	RequestID  string `query:"requestId"`
+	AppVersion string `query:"appVersion"`
+	MinStatus  int    `query:"minStatus" validate:"omitempty,min=0"`
+	MaxStatus  int    `query:"maxStatus" validate:"omitempty,min=0"`
+	IDs        string `query:"ids" doc:"Comma separated request log IDs"`
+	Limit      int    `query:"limit" validate:"omitempty,min=0"`
+}
+
+func ExportRequestLogs(c *fiber.Ctx) error {
+	params, err := binding.Bind[RequestLogExportQueryParams](c)
+	if err != nil {
+		return err
+	}
+
+	filter, err := requestlogs.ParseFilter(func(key string) string { return c.Query(key) })
+	if err != nil {
+		return apierror.BadRequest(err.Error())
+	}
+
+	if params.Format == "" {
+		params.Format = requestlogs.FormatNDJSON
+	}
+	options := requestlogs.ExportOptions{
+		Format:    params.Format,
+		Gzip:      params.Gzip,
+		Redaction: requestlogs.DefaultRedactionPolicy(),
+	}
+	if err := options.Validate(); err != nil {
+		return apierror.BadRequest(err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, options.ContentType())
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", options.FileName(time.Now())))
+
+	// The fiber context is recycled once the handler returns, so the stream
+	// writer gets its own context carrying the request ID
+	ctx := utils.WithRequestID(context.Background(), utils.GetRequestID(c.UserContext()))
+	adminID := getUserId(c)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		count, err := requestlogs.Export(ctx, DB, filter, options, w)
+		if err != nil {
+			logger.ErrorContext(ctx, "Request log export failed", "error", err, "rows", count, "admin_id", adminID)
+			return
+		}
+
+		if err := w.Flush(); err != nil {
+			logger.WarnContext(ctx, "Request log export was not fully delivered", "error", err, "rows", count, "admin_id", adminID)
+			return
+		}
+
+		logger.InfoContext(ctx, "Request log export completed", "rows", count, "admin_id", adminID, "format", options.Format)
+	})
+
+	return nil
+}
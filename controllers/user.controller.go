@@ -4,6 +4,11 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
+type MeResponse struct {
+	Status string `json:"status"`
+	User   string `json:"user"`
+}
+
 func GetMe(c *fiber.Ctx) error {
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": "success", "user": "user"})
+	return c.Status(fiber.StatusOK).JSON(MeResponse{Status: "success", User: "user"})
 }
@@ -1,39 +1,74 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"log"
 
+	"github.com/bparsons094/go-server-base/lifecycle"
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/bparsons094/go-server-base/tracing"
 	"github.com/bparsons094/go-server-base/utils"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	gormLogger "gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+var logger = logging.For("db")
+
 func ConnectDB(config utils.Config) *gorm.DB {
 	var err error
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", config.DBHost, config.DBUser, config.DBPassword, config.DBName, config.DBPort)
 
-	var logLevel logger.LogLevel
-	if config.DBLogging == "info" {
-		logLevel = logger.Info
-	} else {
-		logLevel = logger.Warn
-	}
+	// Slow queries are reported by QueryStatsPlugin, which also knows the request
+	sqlLogger := logging.NewGormLogger("db", gormLogLevel(config), 0)
+	utils.SubscribeConfig(func(previous, current utils.Config) {
+		if previous.DBLogging != current.DBLogging {
+			sqlLogger.SetLevel(gormLogLevel(current))
+		}
+	})
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logLevel), PrepareStmt: true})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: sqlLogger, PrepareStmt: true})
 	if err != nil {
-		log.Fatal("Failed to connect to the Database")
+		logging.Fatal(logger, "Failed to connect to the Database", "error", err)
+	}
+
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		logging.Fatal(logger, "Failed to register the tracing plugin", "error", err)
+	}
+
+	queryStats := QueryStatsPlugin{SlowThreshold: config.DBSlowQueryThreshold, RepeatedQueryThreshold: config.DBRepeatedQueryThreshold}
+	if err := db.Use(queryStats); err != nil {
+		logging.Fatal(logger, "Failed to register the query stats plugin", "error", err)
 	}
 
 	SetDatabase(db)
 
-	log.Println("Connected Successfully to the Database")
+	lifecycle.Register(lifecycle.Hook{
+		Name:            "database",
+		Order:           lifecycle.OrderDatabase,
+		AfterStragglers: true,
+		Stop: func(ctx context.Context) error {
+			sqlDB, err := db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.Close()
+		},
+	})
+
+	logger.Info("Connected Successfully to the Database")
 	return DB
 }
 
+func gormLogLevel(config utils.Config) gormLogger.LogLevel {
+	if config.DBLogging == "info" {
+		return gormLogger.Info
+	}
+	return gormLogger.Warn
+}
+
 func SetDatabase(db *gorm.DB) {
 	DB = db
 }
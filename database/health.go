@@ -0,0 +1,19 @@
+package database
+
+import (
+	"context"
+	"errors"
+)
+
+func HealthCheck(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("database is not connected")
+	}
+
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
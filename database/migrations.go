@@ -2,8 +2,8 @@ package database
 
 import (
 	"fmt"
-	"log"
 
+	"github.com/bparsons094/go-server-base/logging"
 	"github.com/bparsons094/go-server-base/models"
 	"github.com/bparsons094/go-server-base/utils"
 	"gorm.io/driver/postgres"
@@ -11,56 +11,56 @@ import (
 )
 
 func CreateNewDB(config utils.Config) {
-	log.Println("Creating New DB")
+	logger.Info("Creating New DB")
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=postgres port=%s sslmode=disable TimeZone=America/Chicago", config.DBHost, config.DBUser, config.DBPassword, config.DBPort)
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{SkipDefaultTransaction: true})
 
 	if err != nil {
-		log.Fatalf("Error connecting to the database server: %v", err)
+		logging.Fatal(logger, "Error connecting to the database server", "error", err)
 	}
 
 	createDBSQL := fmt.Sprintf("CREATE DATABASE \"%s\" WITH OWNER = \"%s\";", config.DBName, config.DBUser)
 	if err := db.Exec(createDBSQL).Error; err != nil {
-		log.Fatalf("Error creating the database: %v", err)
+		logging.Fatal(logger, "Error creating the database", "error", err)
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Error closing the connection: %v", err)
+		logging.Fatal(logger, "Error closing the connection", "error", err)
 	}
 
 	if err := sqlDB.Close(); err != nil {
-		log.Fatalf("Error closing the connection: %v", err)
+		logging.Fatal(logger, "Error closing the connection", "error", err)
 	}
 }
 
 func FullReset(config utils.Config) {
-	log.Println("Running Full Reset")
+	logger.Info("Running Full Reset")
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=postgres port=%s sslmode=disable TimeZone=America/Chicago", config.DBHost, config.DBUser, config.DBPassword, config.DBPort)
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{SkipDefaultTransaction: true})
 
 	if err != nil {
-		log.Fatalf("Error connecting to the database: %v", err)
+		logging.Fatal(logger, "Error connecting to the database", "error", err)
 	}
 
 	dropDBSQL := fmt.Sprintf("DROP DATABASE IF EXISTS \"%s\" WITH (FORCE);", config.DBName)
 	if err := db.Exec(dropDBSQL).Error; err != nil {
-		log.Fatalf("Error dropping the database: %v", err)
+		logging.Fatal(logger, "Error dropping the database", "error", err)
 	}
 
 	createDBSQL := fmt.Sprintf("CREATE DATABASE \"%s\" WITH OWNER = \"%s\";", config.DBName, config.DBUser)
 	if err := db.Exec(createDBSQL).Error; err != nil {
-		log.Fatalf("Error creating the database: %v", err)
+		logging.Fatal(logger, "Error creating the database", "error", err)
 	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Error closing the connection: %v", err)
+		logging.Fatal(logger, "Error closing the connection", "error", err)
 	}
 	if err := sqlDB.Close(); err != nil {
-		log.Fatalf("Error closing the connection: %v", err)
+		logging.Fatal(logger, "Error closing the connection", "error", err)
 	}
 }
 
@@ -68,22 +68,29 @@ var modelsTOCreateOnly = []interface{}{}
 
 var modelsToMigrate = []interface{}{
 	&models.User{},
+	&models.RequestLog{},
+	&models.HourlyRequestRollup{},
+	&models.DailyRequestRollup{},
+	&models.RollupWatermark{},
+	&models.RateLimitBucket{},
+	&models.CSPReport{},
+	&models.IdempotencyKey{},
 }
 
 func CreateAllTables(db *gorm.DB) {
 	db.DisableForeignKeyConstraintWhenMigrating = true
-	log.Println("Creating Tables")
+	logger.Info("Creating Tables")
 	for _, model := range modelsToMigrate {
-		log.Printf("Creating table for %T", model)
+		logger.Info("Creating table", "model", fmt.Sprintf("%T", model))
 		if err := db.Migrator().CreateTable(model); err != nil {
-			log.Printf("Error creating table for %T: %v", model, err)
+			logger.Error("Error creating table", "model", fmt.Sprintf("%T", model), "error", err)
 		}
 	}
 
 	for _, model := range modelsTOCreateOnly {
-		log.Printf("Creating table for %T", model)
+		logger.Info("Creating table", "model", fmt.Sprintf("%T", model))
 		if err := db.Migrator().CreateTable(model); err != nil {
-			log.Printf("Error creating table for %T: %v", model, err)
+			logger.Error("Error creating table", "model", fmt.Sprintf("%T", model), "error", err)
 		}
 	}
 
@@ -91,29 +98,29 @@ func CreateAllTables(db *gorm.DB) {
 }
 
 func RunAllAutoMigrations(db *gorm.DB) {
-	log.Println("Running Auto Migrations")
+	logger.Info("Running Auto Migrations")
 	for _, model := range modelsToMigrate {
-		log.Printf("Migrating %T", model)
+		logger.Info("Migrating", "model", fmt.Sprintf("%T", model))
 		if err := db.Migrator().AutoMigrate(model); err != nil {
-			log.Printf("Error migrating %T: %v", model, err)
+			logger.Error("Error migrating", "model", fmt.Sprintf("%T", model), "error", err)
 		}
 	}
 }
 
 func RunAllDropAddTables(db *gorm.DB) {
-	log.Println("Running Drop Add Tables")
+	logger.Info("Running Drop Add Tables")
 	for _, model := range modelsToMigrate {
-		log.Printf("Dropping and Adding table for %T", model)
+		logger.Info("Dropping and adding table", "model", fmt.Sprintf("%T", model))
 		if err := db.Migrator().DropTable(model); err != nil {
-			log.Printf("Error dropping table for %T: %v", model, err)
+			logger.Error("Error dropping table", "model", fmt.Sprintf("%T", model), "error", err)
 		}
 		if err := db.Migrator().CreateTable(model); err != nil {
-			log.Printf("Error creating table for %T: %v", model, err)
+			logger.Error("Error creating table", "model", fmt.Sprintf("%T", model), "error", err)
 		}
 	}
 }
 
 func InstallExtensions(db *gorm.DB) {
-	log.Println("Installing Extensions")
+	logger.Info("Installing Extensions")
 	db.Exec("CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\";")
 }
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bparsons094/go-server-base/metrics"
+	"gorm.io/gorm"
+	gormUtils "gorm.io/gorm/utils"
+)
+
+const queryStartKey = "query_stats:start"
+
+type queryStatsKey struct{}
+
+// QueryStats accumulates the queries run with one request's context. It is
+// safe for concurrent use since handlers may query from several goroutines.
+type QueryStats struct {
+	mu               sync.Mutex
+	count            int
+	duration         time.Duration
+	slowCount        int
+	statements       map[string]int
+	repeatedQueries  []string
+	repeatedMinCount int
+}
+
+type QueryStatsSnapshot struct {
+	Count     int
+	Duration  time.Duration
+	SlowCount int
+	// Statements that ran at least the repeated query threshold, the usual
+	// sign of an N+1 loop
+	RepeatedQueries []string
+}
+
+// WithQueryStats attaches a fresh QueryStats to ctx
+func WithQueryStats(ctx context.Context) (context.Context, *QueryStats) {
+	stats := &QueryStats{statements: map[string]int{}}
+	return context.WithValue(ctx, queryStatsKey{}, stats), stats
+}
+
+func QueryStatsFrom(ctx context.Context) *QueryStats {
+	if ctx == nil {
+		return nil
+	}
+	stats, _ := ctx.Value(queryStatsKey{}).(*QueryStats)
+	return stats
+}
+
+func (s *QueryStats) Snapshot() QueryStatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return QueryStatsSnapshot{
+		Count:           s.count,
+		Duration:        s.duration,
+		SlowCount:       s.slowCount,
+		RepeatedQueries: append([]string(nil), s.repeatedQueries...),
+	}
+}
+
+// record returns true the first time statement reaches repeatThreshold
+func (s *QueryStats) record(statement string, elapsed time.Duration, slow bool, repeatThreshold int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.count++
+	s.duration += elapsed
+	if slow {
+		s.slowCount++
+	}
+
+	s.statements[statement]++
+	if repeatThreshold > 0 && s.statements[statement] == repeatThreshold {
+		s.repeatedQueries = append(s.repeatedQueries, statement)
+		return true
+	}
+	return false
+}
+
+// QueryStatsPlugin times every query, flags slow ones with their SQL and
+// caller, and feeds the QueryStats on the statement context when present.
+type QueryStatsPlugin struct {
+	SlowThreshold time.Duration
+	// The same statement running this many times in one request is reported
+	// as a likely N+1, 0 disables the check
+	RepeatedQueryThreshold int
+}
+
+func (QueryStatsPlugin) Name() string {
+	return "query_stats"
+}
+
+func (p QueryStatsPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	registrations := []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+
+	for _, registration := range registrations {
+		if err := registration.before("query_stats:before_"+registration.operation, startTimer); err != nil {
+			return err
+		}
+		if err := registration.after("query_stats:after_"+registration.operation, p.observe(registration.operation)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startTimer(db *gorm.DB) {
+	db.InstanceSet(queryStartKey, time.Now())
+}
+
+func (p QueryStatsPlugin) observe(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		elapsed := time.Since(value.(time.Time))
+
+		metrics.DBQueries.WithLabelValues(operation).Inc()
+		metrics.DBQueryDuration.WithLabelValues(operation).Observe(elapsed.Seconds())
+
+		ctx := db.Statement.Context
+		statement := db.Statement.SQL.String()
+
+		slow := p.SlowThreshold > 0 && elapsed > p.SlowThreshold
+		if slow {
+			metrics.DBSlowQueries.WithLabelValues(operation).Inc()
+			// Bound values can hold passwords and tokens, so only the
+			// placeholders are logged
+			logger.WarnContext(ctx, "Slow query",
+				"sql", statement,
+				"rows", db.Statement.RowsAffected,
+				"duration_ms", float64(elapsed.Microseconds())/1000,
+				"threshold", p.SlowThreshold,
+				"caller", gormUtils.FileWithLineNum(),
+			)
+		}
+
+		stats := QueryStatsFrom(ctx)
+		if stats == nil {
+			return
+		}
+
+		if stats.record(statement, elapsed, slow, p.RepeatedQueryThreshold) {
+			metrics.DBRepeatedQueries.Inc()
+			logger.WarnContext(ctx, "Possible N+1 query, statement repeated within one request",
+				"sql", statement,
+				"count", p.RepeatedQueryThreshold,
+				"caller", gormUtils.FileWithLineNum(),
+			)
+		}
+	}
+}
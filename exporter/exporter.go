@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+
+	"github.com/bparsons094/go-server-base/database"
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/bparsons094/go-server-base/requestlogs"
+	"github.com/bparsons094/go-server-base/utils"
+)
+
+var logger = logging.For("exporter")
+
+// Streams request logs matching a filter as NDJSON or CSV, e.g.
+//
+//	go run ./exporter -userId <uuid> -from 2024-01-01T00:00:00Z -to 2024-02-01T00:00:00Z -format csv -gzip -out audit.csv.gz
+func main() {
+	format := flag.String("format", requestlogs.FormatNDJSON, "output format: ndjson or csv")
+	gzip := flag.Bool("gzip", false, "gzip the output")
+	out := flag.String("out", "", "output file, defaults to stdout")
+
+	filterValues := map[string]*string{}
+	for _, key := range requestlogs.FilterKeys {
+		filterValues[key] = flag.String(key, "", "filter by "+key)
+	}
+	flag.Parse()
+
+	config := utils.LoadConfig("./")
+	// Keep stdout clean for the export itself
+	logging.InitWithOutput(config, os.Stderr)
+
+	filter, err := requestlogs.ParseFilter(func(key string) string {
+		if value, ok := filterValues[key]; ok {
+			return *value
+		}
+		return ""
+	})
+	if err != nil {
+		logging.Fatal(logger, "Invalid filter", "error", err)
+	}
+
+	options := requestlogs.ExportOptions{
+		Format:    *format,
+		Gzip:      *gzip,
+		Redaction: requestlogs.DefaultRedactionPolicy(),
+	}
+	if err := options.Validate(); err != nil {
+		logging.Fatal(logger, "Invalid options", "error", err)
+	}
+
+	var output io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			logging.Fatal(logger, "Error creating output file", "error", err)
+		}
+		defer file.Close()
+		output = file
+	}
+
+	db := database.ConnectDB(config)
+
+	count, err := requestlogs.Export(context.Background(), db, filter, options, output)
+	if err != nil {
+		logging.Fatal(logger, "Export failed", "error", err, "rows", count)
+	}
+
+	logger.Info("Export completed", "rows", count)
+}
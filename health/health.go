@@ -0,0 +1,182 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	defaultTimeout  = 2 * time.Second
+	defaultCacheTTL = 5 * time.Second
+)
+
+type Check func(ctx context.Context) error
+
+type Options struct {
+	// How long a single run may take before it counts as down
+	Timeout time.Duration
+	// Results are reused for this long so frequent probes don't hammer dependencies
+	CacheTTL time.Duration
+	// Critical checks gate readiness, the rest only show up in the detailed report
+	Critical bool
+}
+
+type Result struct {
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	Critical   bool      `json:"critical"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs float64   `json:"durationMs"`
+	CheckedAt  time.Time `json:"checkedAt"`
+}
+
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+type registeredCheck struct {
+	name    string
+	check   Check
+	options Options
+
+	// Held while running so concurrent probes wait for one run and share its result
+	mutex  sync.Mutex
+	result *Result
+}
+
+type Registry struct {
+	mutex  sync.RWMutex
+	checks map[string]*registeredCheck
+}
+
+func NewRegistry() *Registry {
+	return &Registry{checks: map[string]*registeredCheck{}}
+}
+
+var defaultRegistry = NewRegistry()
+
+// Register adds a named check to the default registry, replacing any check
+// already registered under that name
+func Register(name string, check Check, options Options) {
+	defaultRegistry.Register(name, check, options)
+}
+
+func Ready(ctx context.Context) Report {
+	return defaultRegistry.Ready(ctx)
+}
+
+func Detailed(ctx context.Context) Report {
+	return defaultRegistry.Detailed(ctx)
+}
+
+func (r *Registry) Register(name string, check Check, options Options) {
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+	if options.CacheTTL <= 0 {
+		options.CacheTTL = defaultCacheTTL
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.checks[name] = &registeredCheck{name: name, check: check, options: options}
+}
+
+// Ready runs only the critical checks
+func (r *Registry) Ready(ctx context.Context) Report {
+	return r.run(ctx, true)
+}
+
+// Detailed runs every check
+func (r *Registry) Detailed(ctx context.Context) Report {
+	return r.run(ctx, false)
+}
+
+func (r *Registry) run(ctx context.Context, criticalOnly bool) Report {
+	r.mutex.RLock()
+	checks := make([]*registeredCheck, 0, len(r.checks))
+	for _, check := range r.checks {
+		if !criticalOnly || check.options.Critical {
+			checks = append(checks, check)
+		}
+	}
+	r.mutex.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check *registeredCheck) {
+			defer wg.Done()
+			results[i] = check.run(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+
+	report := Report{Status: StatusUp, Checks: results}
+	for _, result := range results {
+		if result.Critical && result.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (c *registeredCheck) run(ctx context.Context) Result {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.result != nil && time.Since(c.result.CheckedAt) < c.options.CacheTTL {
+		return *c.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.options.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := runWithTimeout(ctx, c.check)
+
+	result := Result{
+		Name:       c.name,
+		Status:     StatusUp,
+		Critical:   c.options.Critical,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+		CheckedAt:  time.Now(),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	c.result = &result
+	return result
+}
+
+// Checks that ignore their context still can't hold a probe past the timeout
+func runWithTimeout(ctx context.Context, check Check) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- fmt.Errorf("check panicked: %v", recovered)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check timed out: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func up(ctx context.Context) error { return nil }
+
+func down(ctx context.Context) error { return errors.New("connection refused") }
+
+func TestReadyOnlyRunsCriticalChecks(t *testing.T) {
+	r := NewRegistry()
+	r.Register("database", up, Options{Critical: true})
+	r.Register("cache", down, Options{})
+
+	ready := r.Ready(context.Background())
+	if ready.Status != StatusUp || len(ready.Checks) != 1 || ready.Checks[0].Name != "database" {
+		t.Errorf("Ready() = %+v, want only the database check, up", ready)
+	}
+
+	// A failing non critical check shows up without failing the report
+	detailed := r.Detailed(context.Background())
+	if detailed.Status != StatusUp || len(detailed.Checks) != 2 {
+		t.Fatalf("Detailed() = %+v, want both checks, up", detailed)
+	}
+	if cache := detailed.Checks[0]; cache.Name != "cache" || cache.Status != StatusDown || cache.Error != "connection refused" {
+		t.Errorf("cache result = %+v, want it down with its error", cache)
+	}
+}
+
+func TestCriticalFailureIsDown(t *testing.T) {
+	r := NewRegistry()
+	r.Register("database", down, Options{Critical: true})
+
+	if report := r.Ready(context.Background()); report.Status != StatusDown {
+		t.Errorf("Ready() status = %s, want %s", report.Status, StatusDown)
+	}
+}
+
+func TestResultsAreCached(t *testing.T) {
+	var runs atomic.Int32
+	r := NewRegistry()
+	r.Register("counted", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}, Options{CacheTTL: time.Hour})
+
+	for i := 0; i < 3; i++ {
+		r.Detailed(context.Background())
+	}
+	if got := runs.Load(); got != 1 {
+		t.Errorf("check ran %d times, want 1 within the cache TTL", got)
+	}
+}
+
+func TestTimeoutAndPanic(t *testing.T) {
+	r := NewRegistry()
+	r.Register("stuck", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}, Options{Timeout: 20 * time.Millisecond, Critical: true})
+	r.Register("panics", func(ctx context.Context) error {
+		panic("boom")
+	}, Options{})
+
+	start := time.Now()
+	report := r.Detailed(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Detailed() took %s, want the stuck check cut off", elapsed)
+	}
+	if report.Status != StatusDown {
+		t.Errorf("status = %s, want %s", report.Status, StatusDown)
+	}
+
+	errs := map[string]string{}
+	for _, result := range report.Checks {
+		errs[result.Name] = result.Error
+	}
+	if !strings.Contains(errs["stuck"], "timed out") {
+		t.Errorf("stuck error = %q, want a timeout", errs["stuck"])
+	}
+	if !strings.Contains(errs["panics"], "panicked: boom") {
+		t.Errorf("panics error = %q, want the panic", errs["panics"])
+	}
+}
@@ -0,0 +1,204 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bparsons094/go-server-base/logging"
+)
+
+// Hooks start in ascending order and stop in descending order, so traffic
+// stops first and the database closes last
+const (
+	OrderDatabase         = 10
+	OrderTracing          = 20
+	OrderScheduler        = 30
+	OrderRequestLogWriter = 40
+	OrderWebSocket        = 50
+	OrderHTTPServer       = 60
+	OrderFileWatchers     = 70
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Every hook still to stop keeps at least this much of the shutdown budget,
+// so one slow hook can't leave the rest with an expired context
+const minStopTimeout = time.Second
+
+var logger = logging.For("lifecycle")
+
+type Hook struct {
+	Name  string
+	Order int
+	// Bounds each of Start and Stop, defaults to 10 seconds. Stop may get
+	// less when the shutdown budget is running out.
+	Timeout time.Duration
+	Start   func(ctx context.Context) error
+	Stop    func(ctx context.Context) error
+	// AfterStragglers holds Stop until the stop hooks before it have returned,
+	// including ones that ran past their timeout, e.g. so the database isn't
+	// closed under jobs the scheduler is still waiting on
+	AfterStragglers bool
+}
+
+type Manager struct {
+	mutex   sync.Mutex
+	hooks   []Hook
+	started []Hook
+}
+
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+var defaultManager = NewManager()
+
+// Register adds a hook to the default manager
+func Register(hook Hook) {
+	defaultManager.Register(hook)
+}
+
+func Start(ctx context.Context) error {
+	return defaultManager.Start(ctx)
+}
+
+func Stop(ctx context.Context) error {
+	return defaultManager.Stop(ctx)
+}
+
+func (m *Manager) Register(hook Hook) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.hooks = append(m.hooks, hook)
+}
+
+// Start runs every start hook in order. When one fails, the hooks already
+// started are stopped again before the error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mutex.Lock()
+	hooks := append([]Hook(nil), m.hooks...)
+	m.mutex.Unlock()
+
+	sort.SliceStable(hooks, func(i, j int) bool { return hooks[i].Order < hooks[j].Order })
+
+	for _, hook := range hooks {
+		if hook.Start != nil {
+			hookCtx, cancel := context.WithTimeout(ctx, timeoutOf(hook))
+			err := run(hookCtx, hook.Start, new(sync.WaitGroup))
+			cancel()
+			if err != nil {
+				err = fmt.Errorf("starting %s: %w", hook.Name, err)
+				return errors.Join(err, m.Stop(ctx))
+			}
+		}
+
+		m.mutex.Lock()
+		m.started = append(m.started, hook)
+		m.mutex.Unlock()
+	}
+	return nil
+}
+
+// Stop runs the stop hooks of everything started, in reverse order. A hook
+// that fails or times out doesn't keep the rest from stopping. Each hook gets
+// its own timeout, cut short when needed so that the hooks after it still get
+// minStopTimeout before ctx's deadline.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mutex.Lock()
+	hooks := m.started
+	m.started = nil
+	m.mutex.Unlock()
+
+	remaining := 0
+	for _, hook := range hooks {
+		if hook.Stop != nil {
+			remaining++
+		}
+	}
+
+	// Stop hooks that are still running, whether or not they timed out
+	var stragglers sync.WaitGroup
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if hook.Stop == nil {
+			continue
+		}
+		remaining--
+
+		start := time.Now()
+		hookCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stopTimeout(ctx, hook, remaining))
+		if hook.AfterStragglers {
+			if err := wait(hookCtx, &stragglers); err != nil {
+				logger.Warn("Stopping before earlier hooks finished", "hook", hook.Name, "error", err)
+			}
+		}
+		err := run(hookCtx, hook.Stop, &stragglers)
+		cancel()
+
+		if err != nil {
+			logger.Error("Error stopping", "hook", hook.Name, "error", err)
+			errs = append(errs, fmt.Errorf("stopping %s: %w", hook.Name, err))
+			continue
+		}
+		logger.Info("Stopped", "hook", hook.Name, "duration_ms", time.Since(start).Milliseconds())
+	}
+	return errors.Join(errs...)
+}
+
+func timeoutOf(hook Hook) time.Duration {
+	if hook.Timeout <= 0 {
+		return defaultTimeout
+	}
+	return hook.Timeout
+}
+
+// stopTimeout is the hook's timeout, shortened to leave minStopTimeout of
+// ctx's deadline for each of the remaining hooks
+func stopTimeout(ctx context.Context, hook Hook, remaining int) time.Duration {
+	timeout := timeoutOf(hook)
+	if deadline, ok := ctx.Deadline(); ok {
+		if left := time.Until(deadline) - time.Duration(remaining)*minStopTimeout; left < timeout {
+			timeout = max(left, min(minStopTimeout, timeout))
+		}
+	}
+	return timeout
+}
+
+// run returns when fn does or ctx is done, whichever comes first, even if fn
+// ignores its context. fn is tracked in running until it actually returns.
+func run(ctx context.Context, fn func(context.Context) error, running *sync.WaitGroup) error {
+	done := make(chan error, 1)
+	running.Add(1)
+	go func() {
+		defer running.Done()
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func wait(ctx context.Context, running *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
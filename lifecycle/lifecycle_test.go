@@ -0,0 +1,90 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStopLeavesBudgetForLaterHooks(t *testing.T) {
+	m := NewManager()
+
+	var laterCtxErr error
+	m.Register(Hook{Name: "later", Order: 1, Stop: func(ctx context.Context) error {
+		laterCtxErr = ctx.Err()
+		return nil
+	}})
+	m.Register(Hook{Name: "slow", Order: 2, Timeout: time.Minute, Stop: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := m.Stop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the slow hook to time out, got %v", err)
+	}
+	if laterCtxErr != nil {
+		t.Fatalf("later hook got an expired context: %v", laterCtxErr)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("slow hook was not cut short, Stop took %s", elapsed)
+	}
+}
+
+func TestAfterStragglersWaitsForTimedOutHooks(t *testing.T) {
+	m := NewManager()
+
+	var jobsDone atomic.Bool
+	var closedAfterJobs bool
+	m.Register(Hook{Name: "database", Order: 1, Timeout: 5 * time.Second, AfterStragglers: true, Stop: func(ctx context.Context) error {
+		closedAfterJobs = jobsDone.Load()
+		return nil
+	}})
+	m.Register(Hook{Name: "scheduler", Order: 2, Timeout: 100 * time.Millisecond, Stop: func(ctx context.Context) error {
+		// Ignores its context, like a scheduler waiting on running jobs
+		time.Sleep(500 * time.Millisecond)
+		jobsDone.Store(true)
+		return nil
+	}})
+
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Stop(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the scheduler hook to time out, got %v", err)
+	}
+	if !closedAfterJobs {
+		t.Fatal("database hook ran before the scheduler hook returned")
+	}
+}
+
+func TestStartFailureStopsStartedHooks(t *testing.T) {
+	m := NewManager()
+
+	stopped := false
+	m.Register(Hook{Name: "first", Order: 1, Stop: func(ctx context.Context) error {
+		stopped = true
+		return nil
+	}})
+	m.Register(Hook{Name: "second", Order: 2, Start: func(ctx context.Context) error {
+		return errors.New("boom")
+	}})
+
+	if err := m.Start(context.Background()); err == nil {
+		t.Fatal("expected Start to fail")
+	}
+	if !stopped {
+		t.Fatal("hooks started before the failure were not stopped")
+	}
+}
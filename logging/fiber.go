@@ -0,0 +1,14 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/bparsons094/go-server-base/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Context returns the request's user context annotated with the matched route
+// template, for use with the *Context logging methods inside handlers.
+func Context(c *fiber.Ctx) context.Context {
+	return utils.WithRoute(c.UserContext(), c.Route().Path)
+}
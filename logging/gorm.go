@@ -0,0 +1,100 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	gormUtils "gorm.io/gorm/utils"
+)
+
+// GormLogger adapts GORM's logger interface to the shared slog sink so SQL
+// logs carry the same structure and contextual fields as the rest of the app.
+type GormLogger struct {
+	logger        *slog.Logger
+	level         *atomic.Int32
+	slowThreshold time.Duration
+}
+
+func NewGormLogger(component string, level logger.LogLevel, slowThreshold time.Duration) *GormLogger {
+	gormLogger := &GormLogger{
+		logger:        For(component),
+		level:         &atomic.Int32{},
+		slowThreshold: slowThreshold,
+	}
+	gormLogger.SetLevel(level)
+	return gormLogger
+}
+
+// SetLevel changes the level in place, for config reloads. Sessions created
+// with LogMode keep their own level.
+func (l *GormLogger) SetLevel(level logger.LogLevel) {
+	l.level.Store(int32(level))
+}
+
+func (l *GormLogger) logLevel() logger.LogLevel {
+	return logger.LogLevel(l.level.Load())
+}
+
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *l
+	clone.level = &atomic.Int32{}
+	clone.SetLevel(level)
+	return &clone
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.logLevel() >= logger.Info {
+		l.logger.InfoContext(ctx, fmt.Sprintf(msg, data...), "caller", gormUtils.FileWithLineNum())
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.logLevel() >= logger.Warn {
+		l.logger.WarnContext(ctx, fmt.Sprintf(msg, data...), "caller", gormUtils.FileWithLineNum())
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.logLevel() >= logger.Error {
+		l.logger.ErrorContext(ctx, fmt.Sprintf(msg, data...), "caller", gormUtils.FileWithLineNum())
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.logLevel() <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+
+	switch {
+	case err != nil && l.logLevel() >= logger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.logger.ErrorContext(ctx, "Query failed", queryAttrs(sql, rows, elapsed, slog.String("error", err.Error()))...)
+	case l.slowThreshold != 0 && elapsed > l.slowThreshold && l.logLevel() >= logger.Warn:
+		sql, rows := fc()
+		l.logger.WarnContext(ctx, "Slow query", queryAttrs(sql, rows, elapsed, slog.Duration("threshold", l.slowThreshold))...)
+	case l.logLevel() >= logger.Info:
+		sql, rows := fc()
+		l.logger.InfoContext(ctx, "Query", queryAttrs(sql, rows, elapsed)...)
+	}
+}
+
+func queryAttrs(sql string, rows int64, elapsed time.Duration, extra ...slog.Attr) []any {
+	attrs := []any{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("duration_ms", float64(elapsed.Microseconds())/1000),
+		slog.String("caller", gormUtils.FileWithLineNum()),
+	}
+	for _, attr := range extra {
+		attrs = append(attrs, attr)
+	}
+	return attrs
+}
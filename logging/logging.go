@@ -0,0 +1,219 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bparsons094/go-server-base/utils"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	defaultLevel    slog.LevelVar
+	levelsMutex     sync.RWMutex
+	componentLevels = map[string]slog.Level{}
+
+	// The sink every component logger writes to. Swapped by Init so loggers
+	// created at package init time pick up the configured output.
+	sink atomic.Pointer[slog.Handler]
+)
+
+func init() {
+	setSink(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+// Init configures the shared sink from config: JSON output outside of local,
+// the default level from LOG_LEVEL and component overrides from LOG_LEVELS
+// (e.g. "db=warn,websocket=debug").
+func Init(config utils.Config) {
+	InitWithOutput(config, os.Stdout)
+}
+
+// InitWithOutput is Init with a custom destination, used by CLIs that write
+// their own data to stdout
+func InitWithOutput(config utils.Config, output io.Writer) {
+	format := config.LogFormat
+	if format == "" {
+		format = "json"
+		if config.Environment == "local" {
+			format = "text"
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	if format == "text" {
+		setSink(slog.NewTextHandler(output, options))
+	} else {
+		setSink(slog.NewJSONHandler(output, options))
+	}
+
+	applyLevels(config)
+
+	utils.SubscribeConfig(func(previous, current utils.Config) {
+		if previous.LogLevel != current.LogLevel || previous.LogLevels != current.LogLevels {
+			applyLevels(current)
+		}
+	})
+
+	// Route the standard library logger and slog's default through the same sink
+	slog.SetDefault(For("app"))
+}
+
+// applyLevels sets the default level from LOG_LEVEL and replaces the
+// component overrides with LOG_LEVELS, dropping any set at runtime
+func applyLevels(config utils.Config) {
+	if level, err := ParseLevel(config.LogLevel); err == nil {
+		defaultLevel.Set(level)
+	} else if config.LogLevel != "" {
+		For("logging").Warn("Invalid LOG_LEVEL, defaulting to info", "value", config.LogLevel)
+	}
+
+	levelsMutex.Lock()
+	componentLevels = map[string]slog.Level{}
+	levelsMutex.Unlock()
+
+	for _, pair := range strings.Split(config.LogLevels, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		component, value, found := strings.Cut(pair, "=")
+		level, err := ParseLevel(value)
+		if !found || err != nil {
+			For("logging").Warn("Invalid LOG_LEVELS entry", "value", pair)
+			continue
+		}
+		SetLevel(strings.TrimSpace(component), level)
+	}
+}
+
+// For returns a logger for the named component. Its level can be changed at
+// runtime with SetLevel without recreating the logger.
+func For(component string) *slog.Logger {
+	return slog.New(&componentHandler{component: component})
+}
+
+func SetLevel(component string, level slog.Level) {
+	if component == "" || component == "default" {
+		defaultLevel.Set(level)
+		return
+	}
+
+	levelsMutex.Lock()
+	defer levelsMutex.Unlock()
+	componentLevels[component] = level
+}
+
+func ResetLevel(component string) {
+	levelsMutex.Lock()
+	defer levelsMutex.Unlock()
+	delete(componentLevels, component)
+}
+
+func LevelFor(component string) slog.Level {
+	levelsMutex.RLock()
+	defer levelsMutex.RUnlock()
+
+	if level, ok := componentLevels[component]; ok {
+		return level
+	}
+	return defaultLevel.Level()
+}
+
+// Levels reports the default level and every component override
+func Levels() map[string]string {
+	levelsMutex.RLock()
+	defer levelsMutex.RUnlock()
+
+	levels := map[string]string{"default": defaultLevel.Level().String()}
+	for component, level := range componentLevels {
+		levels[component] = level.String()
+	}
+	return levels
+}
+
+func ParseLevel(value string) (slog.Level, error) {
+	var level slog.Level
+	if strings.TrimSpace(value) == "" {
+		return level, fmt.Errorf("empty log level")
+	}
+
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+		return level, err
+	}
+	return level, nil
+}
+
+func setSink(handler slog.Handler) {
+	sink.Store(&handler)
+}
+
+// componentHandler filters records by the component's level, adds the
+// contextual request fields and forwards to the current sink.
+type componentHandler struct {
+	component string
+	ops       []func(slog.Handler) slog.Handler
+}
+
+func (h *componentHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= LevelFor(h.component)
+}
+
+func (h *componentHandler) Handle(ctx context.Context, record slog.Record) error {
+	// Component and request fields are attached before any groups so they stay top level
+	attrs := append([]slog.Attr{slog.String("component", h.component)}, contextAttrs(ctx)...)
+	handler := (*sink.Load()).WithAttrs(attrs)
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+
+	return handler.Handle(ctx, record)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h *componentHandler) with(op func(slog.Handler) slog.Handler) slog.Handler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &componentHandler{component: h.component, ops: append(ops, op)}
+}
+
+func contextAttrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+
+	var attrs []slog.Attr
+	if requestID := utils.GetRequestID(ctx); requestID != "" {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+	if userID := utils.GetUserID(ctx); userID != "" {
+		attrs = append(attrs, slog.String("user_id", userID))
+	}
+	if route := utils.GetRoute(ctx); route != "" {
+		attrs = append(attrs, slog.String("route", route))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		attrs = append(attrs, slog.String("trace_id", spanContext.TraceID().String()), slog.String("span_id", spanContext.SpanID().String()))
+	}
+	return attrs
+}
+
+// Fatal logs at error level and exits, the slog counterpart of log.Fatal
+func Fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
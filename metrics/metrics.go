@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every collector exposed on /metrics. A dedicated registry
+// keeps third party packages from adding to our exposition implicitly.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	WebSocketConnections = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "websocket_connections_active",
+		Help: "Currently open websocket connections.",
+	})
+
+	WebSocketMessages = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "websocket_messages_total",
+		Help: "Websocket messages by direction (in or out) and message type.",
+	}, []string{"direction", "type"})
+
+	SchedulerJobRuns = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_job_runs_total",
+		Help: "Scheduler job runs by job name and outcome (success or failure).",
+	}, []string{"job", "outcome"})
+
+	SchedulerJobDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scheduler_job_duration_seconds",
+		Help:    "Scheduler job run time by job name.",
+		Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300},
+	}, []string{"job"})
+
+	DBQueries = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_queries_total",
+		Help: "Database queries by GORM operation.",
+	}, []string{"operation"})
+
+	DBQueryDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Database query latency by GORM operation.",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 5},
+	}, []string{"operation"})
+
+	DBSlowQueries = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "db_slow_queries_total",
+		Help: "Queries slower than DB_SLOW_QUERY_THRESHOLD by GORM operation.",
+	}, []string{"operation"})
+
+	DBRepeatedQueries = factory.NewCounter(prometheus.CounterOpts{
+		Name: "db_repeated_queries_total",
+		Help: "Statements that repeated DB_REPEATED_QUERY_THRESHOLD times within one request.",
+	})
+
+	RateLimited = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_rate_limited_total",
+		Help: "Requests rejected by rate limiting, by policy name.",
+	}, []string{"policy"})
+
+	DeprecatedRouteRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_deprecated_route_requests_total",
+		Help: "Requests served by deprecated routes, by method, route template and API version.",
+	}, []string{"method", "route", "version"})
+
+	IdempotentRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_idempotent_requests_total",
+		Help: "Requests sent with an Idempotency-Key, by outcome.",
+	}, []string{"outcome"})
+
+	TLSCertificateExpiry = factory.NewGauge(prometheus.GaugeOpts{
+		Name: "tls_certificate_expiry_timestamp_seconds",
+		Help: "Unix time the served TLS certificate expires.",
+	})
+
+	HTTPRequestQueries = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_db_queries",
+		Help:    "Database queries per HTTP request by method and route template.",
+		Buckets: []float64{0, 1, 2, 5, 10, 20, 50, 100},
+	}, []string{"method", "route"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RegisterDB exposes the connection pool stats from sql.DB.Stats()
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+func ObserveJob(job string, duration time.Duration, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+
+	SchedulerJobRuns.WithLabelValues(job, outcome).Inc()
+	SchedulerJobDuration.WithLabelValues(job).Observe(duration.Seconds())
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+}
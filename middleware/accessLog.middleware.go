@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AccessLog writes one structured line per request. Server errors are logged
+// at error level, client errors at warn and everything else at info.
+func AccessLog(c *fiber.Ctx) error {
+	start := time.Now()
+
+	err := c.Next()
+
+	status := ResponseStatus(c, err)
+
+	level := slog.LevelInfo
+	switch {
+	case status >= fiber.StatusInternalServerError:
+		level = slog.LevelError
+	case status >= fiber.StatusBadRequest:
+		level = slog.LevelWarn
+	}
+
+	attrs := []any{
+		"method", c.Method(),
+		"path", c.Path(),
+		"status", status,
+		"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+		"ip", c.IP(),
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err.Error())
+	}
+
+	logger.Log(logging.Context(c), level, "Request", attrs...)
+	return err
+}
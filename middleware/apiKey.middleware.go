@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/gofiber/fiber/v2"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// APIKey verifies the X-API-Key header against keys, so RateLimit can key
+// service clients by key. keys is read per request so reloaded keys apply
+// immediately. Requests without the header pass through, but an unknown key
+// is rejected rather than limited by IP.
+func APIKey(keys func() []string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		provided := c.Get(APIKeyHeader)
+		if provided == "" {
+			return c.Next()
+		}
+
+		for _, key := range keys() {
+			if tokenMatches(provided, key) {
+				c.Locals("apiKeyID", apiKeyID(key))
+				return c.Next()
+			}
+		}
+		return apierror.Unauthorized("Invalid API key")
+	}
+}
+
+// apiKeyID identifies a key without storing it, e.g. in rate limit buckets
+func apiKeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestAPIKeyRateLimitKey(t *testing.T) {
+	userID := uuid.New()
+
+	app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+	app.Use(APIKey(func() []string { return []string{"first", "second"} }))
+	app.Get("/", func(c *fiber.Ctx) error {
+		if c.Query("user") != "" {
+			c.Locals("UserID", userID)
+		}
+		return c.SendString(rateLimitKey(c))
+	})
+
+	tests := []struct {
+		key        string
+		query      string
+		wantStatus int
+		want       string
+	}{
+		{wantStatus: fiber.StatusOK, want: "ip:0.0.0.0"},
+		{key: "second", wantStatus: fiber.StatusOK, want: "apikey:" + apiKeyID("second")},
+		{key: "second", query: "?user=1", wantStatus: fiber.StatusOK, want: "user:" + userID.String()},
+		{key: "third", wantStatus: fiber.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(fiber.MethodGet, "/"+test.query, nil)
+		if test.key != "" {
+			request.Header.Set(APIKeyHeader, test.key)
+		}
+		response, err := app.Test(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if response.StatusCode != test.wantStatus {
+			t.Errorf("key %q: status = %d, want %d", test.key, response.StatusCode, test.wantStatus)
+			continue
+		}
+		if test.want != "" {
+			if got := readBody(t, response); got != test.want {
+				t.Errorf("key %q%s: rateLimitKey() = %q, want %q", test.key, test.query, got, test.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/bparsons094/go-server-base/metrics"
+	"github.com/gofiber/fiber/v2"
+)
+
+const APIVersionHeader = "API-Version"
+
+// APIVersions are the versions routes are registered under, oldest first
+var APIVersions = []string{"v1", "v2"}
+
+var versionSegment = regexp.MustCompile(`^/(v[0-9]+)(/|$)`)
+
+// APIVersion routes unversioned requests under prefix to a version, so
+// /api/users/getMe is served as /api/v2/users/getMe when the client sends
+// "API-Version: 2", and as the default version otherwise. Versioned paths are
+// left alone. Either way the version is echoed back in API-Version and is
+// available through GetAPIVersion. It must be mounted before the versioned
+// groups so the rewritten path is what they match.
+func APIVersion(prefix string, defaultVersion func() string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		rest := strings.TrimPrefix(c.Path(), prefix)
+
+		if match := versionSegment.FindStringSubmatch(rest); match != nil {
+			// Unknown versions fall through to the 404 handler
+			if isAPIVersion(match[1]) {
+				setAPIVersion(c, match[1])
+			}
+			return c.Next()
+		}
+
+		version := defaultVersion()
+		if requested := c.Get(APIVersionHeader); requested != "" {
+			version = normalizeAPIVersion(requested)
+			if !isAPIVersion(version) {
+				return apierror.BadRequest("Unsupported API version, expected one of " + strings.Join(APIVersions, ", "))
+			}
+		}
+
+		setAPIVersion(c, version)
+		c.Path(prefix + "/" + version + rest)
+		return c.Next()
+	}
+}
+
+func GetAPIVersion(c *fiber.Ctx) string {
+	version, _ := c.Locals("apiVersion").(string)
+	return version
+}
+
+func setAPIVersion(c *fiber.Ctx, version string) {
+	c.Locals("apiVersion", version)
+	c.Set(APIVersionHeader, strings.TrimPrefix(version, "v"))
+	c.Vary(APIVersionHeader)
+}
+
+// normalizeAPIVersion accepts "2", "v2" and "V2"
+func normalizeAPIVersion(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if !strings.HasPrefix(value, "v") {
+		value = "v" + value
+	}
+	return value
+}
+
+func isAPIVersion(version string) bool {
+	for _, known := range APIVersions {
+		if version == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Deprecation describes a route or group that is on its way out. Since and
+// Sunset may be zero when they aren't decided yet. Link points clients at a
+// migration guide or the replacement route.
+type Deprecation struct {
+	Since  time.Time
+	Sunset time.Time
+	Link   string
+}
+
+// Deprecated marks the routes it runs for with Deprecation, Sunset and Link
+// headers (RFC 9745 and RFC 8594) and counts their use, by route and
+// version, so we can tell when they are safe to remove. It works both per
+// route and on a group.
+func Deprecated(deprecation Deprecation) fiber.Handler {
+	value := "true"
+	if !deprecation.Since.IsZero() {
+		value = "@" + strconv.FormatInt(deprecation.Since.Unix(), 10)
+	}
+
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", value)
+		if !deprecation.Sunset.IsZero() {
+			c.Set("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if deprecation.Link != "" {
+			c.Append(fiber.HeaderLink, "<"+deprecation.Link+">; rel=\"deprecation\"")
+		}
+
+		err := c.Next()
+
+		metrics.DeprecatedRouteRequests.WithLabelValues(c.Method(), c.Route().Path, GetAPIVersion(c)).Inc()
+		return err
+	}
+}
@@ -0,0 +1,197 @@
+package middleware
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bparsons094/go-server-base/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CORSPolicy struct {
+	// Exact origins, "*" for any, or wildcard subdomains such as
+	// https://*.example.com. An empty list allows no cross-origin requests, and
+	// "*" is ignored when AllowCredentials is set.
+	AllowOrigins []string
+	AllowMethods []string
+	// Empty reflects the headers a preflight asks for
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type CORSConfig struct {
+	Default CORSPolicy
+	// Route group overrides, e.g. stricter rules for /internal
+	Groups map[string]CORSPolicy
+}
+
+func DefaultCORSConfig(config utils.Config) CORSConfig {
+	origins := utils.SplitList(config.CORSAllowedOrigins)
+	if len(origins) == 0 {
+		origins = []string{config.ClientOrigin}
+	}
+
+	policy := CORSPolicy{
+		AllowOrigins:     origins,
+		AllowMethods:     []string{fiber.MethodGet, fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete, fiber.MethodOptions},
+		AllowHeaders:     []string{fiber.HeaderOrigin, fiber.HeaderContentType, fiber.HeaderAccept, fiber.HeaderAuthorization, RequestIDHeader, IdempotencyKeyHeader, APIVersionHeader},
+		ExposeHeaders:    []string{RequestIDHeader, IdempotentReplayedHeader, APIVersionHeader, "Deprecation", "Sunset", fiber.HeaderLink},
+		AllowCredentials: true,
+		MaxAge:           config.CORSMaxAge,
+	}
+
+	// Debug endpoints are only meant for operators, never for browser apps
+	internal := policy
+	internal.AllowOrigins = nil
+
+	return CORSConfig{
+		Default: policy,
+		Groups:  map[string]CORSPolicy{"/internal": internal},
+	}
+}
+
+type compiledCORSPolicy struct {
+	exact         map[string]bool
+	wildcards     []string
+	any           bool
+	credentials   bool
+	methods       string
+	headers       string
+	exposeHeaders string
+	maxAge        string
+}
+
+type compiledCORSConfig struct {
+	defaultPolicy *compiledCORSPolicy
+	groups        map[string]*compiledCORSPolicy
+}
+
+// CORS applies a CORSConfig that can be swapped with Update while serving
+type CORS struct {
+	config atomic.Pointer[compiledCORSConfig]
+}
+
+func NewCORS(config CORSConfig) *CORS {
+	cors := &CORS{}
+	cors.Update(config)
+	return cors
+}
+
+func (cors *CORS) Update(config CORSConfig) {
+	compiled := &compiledCORSConfig{
+		defaultPolicy: compileCORSPolicy(config.Default),
+		groups:        make(map[string]*compiledCORSPolicy, len(config.Groups)),
+	}
+	for prefix, policy := range config.Groups {
+		compiled.groups[prefix] = compileCORSPolicy(policy)
+	}
+	cors.config.Store(compiled)
+}
+
+func (cors *CORS) Handler(c *fiber.Ctx) error {
+	config := cors.config.Load()
+	policy := policyForPath(c.Path(), config.defaultPolicy, config.groups)
+	preflight := c.Method() == fiber.MethodOptions && c.Get(fiber.HeaderAccessControlRequestMethod) != ""
+
+	// The response depends on Origin even when it is missing or rejected, so
+	// caches must not share it across origins
+	c.Vary(fiber.HeaderOrigin)
+	if preflight {
+		c.Vary(fiber.HeaderAccessControlRequestMethod, fiber.HeaderAccessControlRequestHeaders)
+	}
+
+	origin := c.Get(fiber.HeaderOrigin)
+	if origin == "" {
+		return c.Next()
+	}
+
+	allowOrigin, allowed := policy.allowOrigin(origin)
+	if !allowed {
+		if preflight {
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+		return c.Next()
+	}
+
+	c.Set(fiber.HeaderAccessControlAllowOrigin, allowOrigin)
+	if policy.credentials {
+		c.Set(fiber.HeaderAccessControlAllowCredentials, "true")
+	}
+
+	if !preflight {
+		if policy.exposeHeaders != "" {
+			c.Set(fiber.HeaderAccessControlExposeHeaders, policy.exposeHeaders)
+		}
+		return c.Next()
+	}
+
+	c.Set(fiber.HeaderAccessControlAllowMethods, policy.methods)
+	if policy.headers != "" {
+		c.Set(fiber.HeaderAccessControlAllowHeaders, policy.headers)
+	} else if requested := c.Get(fiber.HeaderAccessControlRequestHeaders); requested != "" {
+		c.Set(fiber.HeaderAccessControlAllowHeaders, requested)
+	}
+	if policy.maxAge != "" {
+		c.Set(fiber.HeaderAccessControlMaxAge, policy.maxAge)
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func compileCORSPolicy(policy CORSPolicy) *compiledCORSPolicy {
+	compiled := &compiledCORSPolicy{
+		exact:         map[string]bool{},
+		credentials:   policy.AllowCredentials,
+		methods:       strings.Join(policy.AllowMethods, ", "),
+		headers:       strings.Join(policy.AllowHeaders, ", "),
+		exposeHeaders: strings.Join(policy.ExposeHeaders, ", "),
+	}
+	if policy.MaxAge > 0 {
+		compiled.maxAge = strconv.Itoa(int(policy.MaxAge.Seconds()))
+	}
+
+	for _, origin := range policy.AllowOrigins {
+		origin = strings.ToLower(strings.TrimRight(strings.TrimSpace(origin), "/"))
+		switch {
+		case origin == "*":
+			// Any site could make credentialed requests as the user
+			compiled.any = !policy.AllowCredentials
+		case strings.Contains(origin, "://*."):
+			compiled.wildcards = append(compiled.wildcards, origin)
+		case origin != "":
+			compiled.exact[origin] = true
+		}
+	}
+	return compiled
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin
+func (policy *compiledCORSPolicy) allowOrigin(origin string) (string, bool) {
+	normalized := strings.ToLower(origin)
+
+	if policy.any {
+		return "*", true
+	}
+
+	if policy.exact[normalized] {
+		return origin, true
+	}
+
+	parsed, err := url.Parse(normalized)
+	if err != nil || parsed.Host == "" {
+		return "", false
+	}
+
+	for _, pattern := range policy.wildcards {
+		scheme, suffix, _ := strings.Cut(pattern, "://*")
+		if parsed.Scheme == scheme && strings.HasSuffix(parsed.Host, suffix) && len(parsed.Host) > len(suffix) {
+			return origin, true
+		}
+	}
+	return "", false
+}
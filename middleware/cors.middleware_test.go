@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCORSAllowOrigin(t *testing.T) {
+	policy := compileCORSPolicy(CORSPolicy{
+		AllowOrigins:     []string{"https://app.example.org", "https://*.example.com"},
+		AllowCredentials: true,
+	})
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.org", true},
+		{"https://APP.example.org", true},
+		{"https://other.example.org", false},
+		{"https://a.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://example.com", false},
+		{"https://.example.com", false},
+		{"https://example.com.evil.io", false},
+		{"https://a.example.com.evil.io", false},
+		{"https://evilexample.com", false},
+		{"http://a.example.com", false},
+		{"null", false},
+	}
+
+	for _, test := range tests {
+		got, allowed := policy.allowOrigin(test.origin)
+		if allowed != test.allowed {
+			t.Errorf("allowOrigin(%q) allowed = %v, want %v", test.origin, allowed, test.allowed)
+		}
+		if allowed && got != test.origin {
+			t.Errorf("allowOrigin(%q) = %q, want the origin reflected", test.origin, got)
+		}
+	}
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	policy := compileCORSPolicy(CORSPolicy{AllowOrigins: []string{"*"}})
+	if got, allowed := policy.allowOrigin("https://anywhere.io"); !allowed || got != "*" {
+		t.Errorf("allowOrigin() = %q, %v, want *", got, allowed)
+	}
+
+	credentialed := compileCORSPolicy(CORSPolicy{AllowOrigins: []string{"*"}, AllowCredentials: true})
+	if _, allowed := credentialed.allowOrigin("https://anywhere.io"); allowed {
+		t.Error("* must not allow origins when credentials are allowed")
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	cors := NewCORS(CORSConfig{
+		Default: CORSPolicy{
+			AllowOrigins:     []string{"https://*.example.com"},
+			AllowMethods:     []string{fiber.MethodGet, fiber.MethodPost},
+			AllowCredentials: true,
+		},
+		Groups: map[string]CORSPolicy{"/internal": {}},
+	})
+
+	app := fiber.New()
+	app.Use(cors.Handler)
+	app.All("/*", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	preflight := func(path, origin string) (int, string) {
+		t.Helper()
+		req := httptest.NewRequest(fiber.MethodOptions, path, nil)
+		req.Header.Set(fiber.HeaderOrigin, origin)
+		req.Header.Set(fiber.HeaderAccessControlRequestMethod, fiber.MethodPost)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, resp.Header.Get(fiber.HeaderAccessControlAllowOrigin)
+	}
+
+	if status, allowOrigin := preflight("/api/v1/users", "https://a.example.com"); status != fiber.StatusNoContent || allowOrigin != "https://a.example.com" {
+		t.Errorf("allowed preflight = %d, %q", status, allowOrigin)
+	}
+	if status, allowOrigin := preflight("/api/v1/users", "https://example.com.evil.io"); status != fiber.StatusForbidden || allowOrigin != "" {
+		t.Errorf("rejected preflight = %d, %q", status, allowOrigin)
+	}
+	if status, _ := preflight("/internal/debug", "https://a.example.com"); status != fiber.StatusForbidden {
+		t.Errorf("/internal preflight = %d, want the group policy to reject it", status)
+	}
+}
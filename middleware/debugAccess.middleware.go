@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const DebugTokenHeader = "X-Debug-Token"
+
+// DebugAccess lets requests carrying the configured debug token through and
+// authenticates everyone else as a regular user. Pair it with
+// RequireAdminOrDebugToken so those users must also be admins.
+func DebugAccess(token string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if tokenMatches(c.Get(DebugTokenHeader), token) {
+			c.Locals("debugTokenAccess", true)
+			return c.Next()
+		}
+
+		return AuthenticateUser(c)
+	}
+}
+
+// MetricsAccess lets scrapers in with "Authorization: Bearer <metricsToken>",
+// which Prometheus supports natively, and falls back to DebugAccess for
+// everyone else. Pair it with RequireAdminOrDebugToken.
+func MetricsAccess(metricsToken string, debugToken string) fiber.Handler {
+	debugAccess := DebugAccess(debugToken)
+
+	return func(c *fiber.Ctx) error {
+		scheme, provided, _ := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+		if scheme == "Bearer" && tokenMatches(provided, metricsToken) {
+			c.Locals("debugTokenAccess", true)
+			return c.Next()
+		}
+
+		return debugAccess(c)
+	}
+}
+
+func tokenMatches(provided string, token string) bool {
+	return token != "" && provided != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+func RequireAdminOrDebugToken(c *fiber.Ctx) error {
+	if granted, _ := c.Locals("debugTokenAccess").(bool); granted {
+		return c.Next()
+	}
+
+	return RequireAdmin(c)
+}
@@ -2,9 +2,9 @@ package middleware
 
 import (
 	"errors"
-	"net/http"
 	"strings"
 
+	"github.com/bparsons094/go-server-base/apierror"
 	"github.com/bparsons094/go-server-base/database"
 	"github.com/bparsons094/go-server-base/models"
 	"github.com/bparsons094/go-server-base/utils"
@@ -23,41 +23,38 @@ func AuthenticateUser(c *fiber.Ctx) error {
 	}
 
 	if token == "" {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
-			"status":  "fail",
-			"message": "You are not logged in",
-		})
+		return apierror.Unauthorized("You are not logged in")
 	}
 
 	sub, err := utils.ValidateToken(token)
 	if err != nil {
-		return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
-			"status":  "error",
-			"message": err.Error(),
-		})
+		unauthorized := apierror.Unauthorized("Invalid or expired token")
+		unauthorized.Cause = err
+		return unauthorized
 	}
 
 	if cachedUser, found := utils.GetUser(sub); found {
-		c.Locals("currentUser", cachedUser)
+		setCurrentUser(c, cachedUser)
 		return c.Next()
 	}
 
 	var user models.User
-	err = database.DB.Where("id = ?", sub).First(&user).Error
+	err = database.DB.WithContext(c.UserContext()).Where("id = ?", sub).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
-				"status":  "error",
-				"message": "User not found",
-			})
-		} else {
-			return c.Status(http.StatusUnauthorized).JSON(fiber.Map{
-				"status":  "error",
-				"message": "Token Error",
-			})
+			return apierror.Unauthorized("User not found")
 		}
+		unauthorized := apierror.Unauthorized("Token Error")
+		unauthorized.Cause = err
+		return unauthorized
 	}
 
-	c.Locals("currentUser", user)
+	setCurrentUser(c, user)
 	return c.Next()
 }
+
+func setCurrentUser(c *fiber.Ctx, user models.User) {
+	c.Locals("currentUser", user)
+	c.Locals("UserID", user.ID)
+	c.SetUserContext(utils.WithUserID(c.UserContext(), user.ID.String()))
+}
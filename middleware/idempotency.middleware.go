@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/bparsons094/go-server-base/metrics"
+	"github.com/bparsons094/go-server-base/models"
+	"github.com/bparsons094/go-server-base/utils"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencyOutcomeStored  = "stored"
+	idempotencyOutcomeReplay  = "replayed"
+	idempotencyOutcomeBusy    = "in_progress"
+	idempotencyOutcomeReused  = "mismatch"
+	idempotencyOutcomeSkipped = "released"
+)
+
+// Headers that describe this particular response rather than the result, so
+// a replay sets them fresh
+var unreplayedHeaders = map[string]bool{
+	"date":                      true,
+	"content-length":            true,
+	"content-encoding":          true,
+	"set-cookie":                true,
+	"x-request-id":              true,
+	"retry-after":               true,
+	"ratelimit-limit":           true,
+	"ratelimit-remaining":       true,
+	"ratelimit-reset":           true,
+	"ratelimit-policy":          true,
+	"idempotent-replayed":       true,
+	"strict-transport-security": true,
+}
+
+var errIdempotencyKeyLost = errors.New("idempotency key was taken over before the response was stored")
+
+// idempotencyStore holds the keys, postgresIdempotencyStore in production
+type idempotencyStore interface {
+	// acquire claims the key for this request, returning false with the
+	// existing record when another request holds or has completed it
+	acquire(ctx context.Context, scope, key, fingerprint string, config utils.Config, now time.Time) (models.IdempotencyKey, bool, error)
+	release(ctx context.Context, scope, key, fingerprint string) error
+	store(ctx context.Context, scope, key, fingerprint string, status int, headers models.JSONB, body []byte) error
+}
+
+// Idempotency stores the first response to an unsafe request sent with an
+// Idempotency-Key and replays it for retries. Keys are scoped per client like
+// RateLimit, so it must run after AuthenticateUser. A retry with a different
+// method, URL or body is rejected, as is one that arrives while the first is
+// still running. Errors and 5xx responses release the key so they can be
+// retried.
+func Idempotency(DB *gorm.DB) fiber.Handler {
+	return idempotency(postgresIdempotencyStore{db: DB})
+}
+
+func idempotency(store idempotencyStore) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" || isSafeMethod(c.Method()) {
+			return c.Next()
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			return apierror.BadRequest("Idempotency-Key must be at most 255 characters")
+		}
+
+		config := utils.GetConfig()
+		ctx := c.UserContext()
+		scope := rateLimitKey(c)
+		fingerprint := requestFingerprint(c)
+
+		record, acquired, err := store.acquire(ctx, scope, key, fingerprint, config, time.Now())
+		if err != nil {
+			// Without the store there is no safe way to tell a retry apart
+			logger.ErrorContext(logging.Context(c), "Error acquiring idempotency key", "error", err)
+			return apierror.New(fiber.StatusServiceUnavailable, "service_unavailable", "Unable to process the request, please retry")
+		}
+
+		if !acquired {
+			switch {
+			case record.Fingerprint != fingerprint:
+				metrics.IdempotentRequests.WithLabelValues(idempotencyOutcomeReused).Inc()
+				return apierror.New(fiber.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was already used for a different request")
+			case record.StatusCode == 0:
+				metrics.IdempotentRequests.WithLabelValues(idempotencyOutcomeBusy).Inc()
+				c.Set(fiber.HeaderRetryAfter, ceilSeconds(time.Until(record.LockedUntil)))
+				return apierror.New(fiber.StatusConflict, "idempotency_key_in_use", "A request with this Idempotency-Key is still being processed")
+			default:
+				metrics.IdempotentRequests.WithLabelValues(idempotencyOutcomeReplay).Inc()
+				return replayIdempotentResponse(c, record)
+			}
+		}
+
+		err = c.Next()
+
+		status := ResponseStatus(c, err)
+		if err != nil || status >= fiber.StatusInternalServerError {
+			metrics.IdempotentRequests.WithLabelValues(idempotencyOutcomeSkipped).Inc()
+			if releaseErr := store.release(ctx, scope, key, fingerprint); releaseErr != nil {
+				logger.ErrorContext(logging.Context(c), "Error releasing idempotency key", "error", releaseErr)
+			}
+			return err
+		}
+
+		response := c.Response()
+		if storeErr := store.store(ctx, scope, key, fingerprint, response.StatusCode(), replayableHeaders(response), append([]byte(nil), response.Body()...)); storeErr != nil {
+			// The key stays locked until IDEMPOTENCY_LOCK_TIMEOUT, then a retry runs again
+			logger.ErrorContext(logging.Context(c), "Error storing idempotent response", "error", storeErr)
+			return nil
+		}
+
+		metrics.IdempotentRequests.WithLabelValues(idempotencyOutcomeStored).Inc()
+		return nil
+	}
+}
+
+func isSafeMethod(method string) bool {
+	return method == fiber.MethodGet || method == fiber.MethodHead || method == fiber.MethodOptions
+}
+
+func requestFingerprint(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method()))
+	hash.Write([]byte{'\n'})
+	hash.Write([]byte(c.OriginalURL()))
+	hash.Write([]byte{'\n'})
+	hash.Write(c.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// canTakeOverIdempotencyKey reports whether a request may claim a key that is
+// already stored: once the record has expired, or when a retry of the same
+// request finds the lock left behind by one that never finished
+func canTakeOverIdempotencyKey(existing models.IdempotencyKey, fingerprint string, now time.Time) bool {
+	expired := !now.Before(existing.ExpiresAt)
+	abandoned := existing.StatusCode == 0 && existing.Fingerprint == fingerprint && !now.Before(existing.LockedUntil)
+	return expired || abandoned
+}
+
+// replayableHeaders returns the response headers a replay sets again
+func replayableHeaders(response *fiber.Response) models.JSONB {
+	headers := models.JSONB{}
+	response.Header.VisitAll(func(name, value []byte) {
+		if !unreplayedHeaders[strings.ToLower(string(name))] {
+			headers[string(name)] = string(value)
+		}
+	})
+	return headers
+}
+
+type postgresIdempotencyStore struct {
+	db *gorm.DB
+}
+
+func (s postgresIdempotencyStore) acquire(ctx context.Context, scope, key, fingerprint string, config utils.Config, now time.Time) (models.IdempotencyKey, bool, error) {
+	var existing models.IdempotencyKey
+	acquired := false
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		record := models.IdempotencyKey{
+			Scope:       scope,
+			Key:         key,
+			Fingerprint: fingerprint,
+			LockedUntil: now.Add(config.IdempotencyLockTimeout),
+			ExpiresAt:   now.Add(config.IdempotencyKeyTTL),
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 1 {
+			acquired = true
+			return nil
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("scope = ? AND key = ?", scope, key).First(&existing).Error; err != nil {
+			return err
+		}
+
+		if !canTakeOverIdempotencyKey(existing, fingerprint, now) {
+			return nil
+		}
+
+		acquired = true
+		return tx.Model(&models.IdempotencyKey{}).Where("scope = ? AND key = ?", scope, key).Updates(map[string]interface{}{
+			"fingerprint":  record.Fingerprint,
+			"status_code":  0,
+			"headers":      models.JSONB{},
+			"body":         nil,
+			"locked_until": record.LockedUntil,
+			"created_at":   now,
+			"expires_at":   record.ExpiresAt,
+		}).Error
+	})
+
+	return existing, acquired, err
+}
+
+func (s postgresIdempotencyStore) release(ctx context.Context, scope, key, fingerprint string) error {
+	return s.db.WithContext(ctx).
+		Where("scope = ? AND key = ? AND fingerprint = ? AND status_code = 0", scope, key, fingerprint).
+		Delete(&models.IdempotencyKey{}).Error
+}
+
+func (s postgresIdempotencyStore) store(ctx context.Context, scope, key, fingerprint string, status int, headers models.JSONB, body []byte) error {
+	result := s.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("scope = ? AND key = ? AND fingerprint = ? AND status_code = 0", scope, key, fingerprint).
+		Updates(map[string]interface{}{
+			"status_code": status,
+			"headers":     headers,
+			"body":        body,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errIdempotencyKeyLost
+	}
+	return nil
+}
+
+func replayIdempotentResponse(c *fiber.Ctx, record models.IdempotencyKey) error {
+	for name, value := range record.Headers {
+		if value, ok := value.(string); ok {
+			c.Set(name, value)
+		}
+	}
+	c.Set(IdempotentReplayedHeader, "true")
+	return c.Status(record.StatusCode).Send(record.Body)
+}
@@ -0,0 +1,287 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/bparsons094/go-server-base/models"
+	"github.com/bparsons094/go-server-base/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// memoryIdempotencyStore follows postgresIdempotencyStore's rules without a
+// database
+type memoryIdempotencyStore struct {
+	mu       sync.Mutex
+	records  map[string]models.IdempotencyKey
+	storeErr error
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: map[string]models.IdempotencyKey{}}
+}
+
+func (s *memoryIdempotencyStore) acquire(ctx context.Context, scope, key, fingerprint string, config utils.Config, now time.Time) (models.IdempotencyKey, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[scope+"\n"+key]; ok && !canTakeOverIdempotencyKey(existing, fingerprint, now) {
+		return existing, false, nil
+	}
+	s.records[scope+"\n"+key] = models.IdempotencyKey{
+		Scope:       scope,
+		Key:         key,
+		Fingerprint: fingerprint,
+		LockedUntil: now.Add(config.IdempotencyLockTimeout),
+		ExpiresAt:   now.Add(config.IdempotencyKeyTTL),
+	}
+	return models.IdempotencyKey{}, true, nil
+}
+
+func (s *memoryIdempotencyStore) release(ctx context.Context, scope, key, fingerprint string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if record := s.records[scope+"\n"+key]; record.Fingerprint == fingerprint && record.StatusCode == 0 {
+		delete(s.records, scope+"\n"+key)
+	}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) store(ctx context.Context, scope, key, fingerprint string, status int, headers models.JSONB, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.storeErr != nil {
+		return s.storeErr
+	}
+	record, ok := s.records[scope+"\n"+key]
+	if !ok || record.Fingerprint != fingerprint || record.StatusCode != 0 {
+		return errIdempotencyKeyLost
+	}
+	record.StatusCode = status
+	record.Headers = headers
+	record.Body = body
+	s.records[scope+"\n"+key] = record
+	return nil
+}
+
+func useIdempotencyConfig(t *testing.T, lockTimeout time.Duration) {
+	t.Helper()
+
+	previous := utils.GetConfig()
+	t.Cleanup(func() { utils.SetConfig(previous) })
+
+	config := previous
+	config.IdempotencyKeyTTL = time.Hour
+	config.IdempotencyLockTimeout = lockTimeout
+	utils.SetConfig(config)
+}
+
+func idempotentRequest(t *testing.T, app *fiber.App, key string, body string) *http.Response {
+	t.Helper()
+
+	request := httptest.NewRequest(fiber.MethodPost, "/orders", strings.NewReader(body))
+	request.Header.Set(IdempotencyKeyHeader, key)
+	response, err := app.Test(request, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func readBody(t *testing.T, response *http.Response) string {
+	t.Helper()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestCanTakeOverIdempotencyKey(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		existing    models.IdempotencyKey
+		fingerprint string
+		want        bool
+	}{
+		{
+			name:        "completed",
+			existing:    models.IdempotencyKey{Fingerprint: "a", StatusCode: 201, LockedUntil: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)},
+			fingerprint: "a",
+		},
+		{
+			name:        "running",
+			existing:    models.IdempotencyKey{Fingerprint: "a", LockedUntil: now.Add(time.Second), ExpiresAt: now.Add(time.Hour)},
+			fingerprint: "a",
+		},
+		{
+			name:        "abandoned",
+			existing:    models.IdempotencyKey{Fingerprint: "a", LockedUntil: now, ExpiresAt: now.Add(time.Hour)},
+			fingerprint: "a",
+			want:        true,
+		},
+		{
+			name:        "abandoned by a different request",
+			existing:    models.IdempotencyKey{Fingerprint: "a", LockedUntil: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)},
+			fingerprint: "b",
+		},
+		{
+			name:        "expired",
+			existing:    models.IdempotencyKey{Fingerprint: "a", StatusCode: 201, ExpiresAt: now},
+			fingerprint: "b",
+			want:        true,
+		},
+	}
+
+	for _, test := range tests {
+		if got := canTakeOverIdempotencyKey(test.existing, test.fingerprint, now); got != test.want {
+			t.Errorf("%s: canTakeOverIdempotencyKey() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	useIdempotencyConfig(t, time.Minute)
+
+	calls := 0
+	app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+	app.Use(idempotency(newMemoryIdempotencyStore()))
+	app.Post("/orders", func(c *fiber.Ctx) error {
+		calls++
+		c.Set("Location", "/orders/1")
+		c.Set(fiber.HeaderSetCookie, "session=secret")
+		c.Set("X-Request-Id", "first")
+		c.Set("RateLimit-Remaining", "9")
+		return c.Status(fiber.StatusCreated).SendString("created")
+	})
+
+	first := idempotentRequest(t, app, "order-1", `{"item":1}`)
+	if first.StatusCode != fiber.StatusCreated || first.Header.Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("first response = %d replayed %q, want a fresh 201", first.StatusCode, first.Header.Get(IdempotentReplayedHeader))
+	}
+
+	replay := idempotentRequest(t, app, "order-1", `{"item":1}`)
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+	if replay.StatusCode != fiber.StatusCreated || readBody(t, replay) != "created" {
+		t.Errorf("replay = %d, want the stored 201 and body", replay.StatusCode)
+	}
+	if replay.Header.Get(IdempotentReplayedHeader) != "true" {
+		t.Error("replay is missing Idempotent-Replayed")
+	}
+	if replay.Header.Get("Location") != "/orders/1" {
+		t.Errorf("Location = %q, want it replayed", replay.Header.Get("Location"))
+	}
+	for _, header := range []string{fiber.HeaderSetCookie, "X-Request-Id", "RateLimit-Remaining"} {
+		if value := replay.Header.Get(header); value != "" {
+			t.Errorf("%s = %q, want it left out of the replay", header, value)
+		}
+	}
+}
+
+func TestIdempotencyRejectsReusedKey(t *testing.T) {
+	useIdempotencyConfig(t, time.Minute)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+	app.Use(idempotency(newMemoryIdempotencyStore()))
+	app.Post("/orders", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	idempotentRequest(t, app, "order-1", `{"item":1}`)
+	response := idempotentRequest(t, app, "order-1", `{"item":2}`)
+	if response.StatusCode != fiber.StatusUnprocessableEntity || !strings.Contains(readBody(t, response), "idempotency_key_reused") {
+		t.Errorf("reused key = %d, want 422 idempotency_key_reused", response.StatusCode)
+	}
+}
+
+func TestIdempotencyRejectsRequestInProgress(t *testing.T) {
+	useIdempotencyConfig(t, time.Minute)
+
+	var concurrent *http.Response
+	app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+	app.Use(idempotency(newMemoryIdempotencyStore()))
+	app.Post("/orders", func(c *fiber.Ctx) error {
+		// A retry arriving while the first request is still running
+		if concurrent == nil {
+			concurrent = idempotentRequest(t, app, "order-1", `{"item":1}`)
+		}
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	idempotentRequest(t, app, "order-1", `{"item":1}`)
+	if concurrent.StatusCode != fiber.StatusConflict || !strings.Contains(readBody(t, concurrent), "idempotency_key_in_use") {
+		t.Errorf("concurrent retry = %d, want 409 idempotency_key_in_use", concurrent.StatusCode)
+	}
+	if retryAfter := concurrent.Header.Get(fiber.HeaderRetryAfter); retryAfter != "60" {
+		t.Errorf("Retry-After = %q, want the 60s left on the lock", retryAfter)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnFailure(t *testing.T) {
+	useIdempotencyConfig(t, time.Minute)
+
+	calls := 0
+	app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+	app.Use(idempotency(newMemoryIdempotencyStore()))
+	app.Post("/orders", func(c *fiber.Ctx) error {
+		calls++
+		switch calls {
+		case 1:
+			return c.SendStatus(fiber.StatusBadGateway)
+		case 2:
+			return apierror.BadRequest("Try again")
+		}
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	for _, want := range []int{fiber.StatusBadGateway, fiber.StatusBadRequest, fiber.StatusCreated} {
+		if response := idempotentRequest(t, app, "order-1", `{"item":1}`); response.StatusCode != want {
+			t.Errorf("attempt %d = %d, want %d", calls, response.StatusCode, want)
+		}
+	}
+	if calls != 3 {
+		t.Errorf("handler ran %d times, want every failed attempt retried", calls)
+	}
+}
+
+func TestIdempotencyTakesOverAbandonedLock(t *testing.T) {
+	useIdempotencyConfig(t, 100*time.Millisecond)
+
+	// The response is never stored, as if the first request's process died
+	store := newMemoryIdempotencyStore()
+	store.storeErr = errors.New("connection lost")
+
+	calls := 0
+	app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+	app.Use(idempotency(store))
+	app.Post("/orders", func(c *fiber.Ctx) error {
+		calls++
+		return c.SendStatus(fiber.StatusCreated)
+	})
+
+	idempotentRequest(t, app, "order-1", `{"item":1}`)
+	if response := idempotentRequest(t, app, "order-1", `{"item":1}`); response.StatusCode != fiber.StatusConflict {
+		t.Fatalf("retry within IDEMPOTENCY_LOCK_TIMEOUT = %d, want 409", response.StatusCode)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	store.storeErr = nil
+	if response := idempotentRequest(t, app, "order-1", `{"item":1}`); response.StatusCode != fiber.StatusCreated || calls != 2 {
+		t.Errorf("retry after IDEMPOTENCY_LOCK_TIMEOUT = %d after %d calls, want the handler to run again", response.StatusCode, calls)
+	}
+}
@@ -1,40 +1,181 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"math/rand"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/bparsons094/go-server-base/buildinfo"
+	"github.com/bparsons094/go-server-base/database"
+	"github.com/bparsons094/go-server-base/logging"
 	"github.com/bparsons094/go-server-base/models"
+	"github.com/bparsons094/go-server-base/requestlogs"
+	"github.com/bparsons094/go-server-base/tracing"
+	"github.com/bparsons094/go-server-base/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-func LogMiddleware(db *gorm.DB) fiber.Handler {
+var logger = logging.For("http")
+
+// More pending writes than this means the database can't keep up with traffic
+const maxPendingRequestLogWrites = 1000
+
+var requestLogWriter struct {
+	pending     atomic.Int64
+	lastFailure atomic.Int64
+	lastError   atomic.Value
+}
+
+// RequestLogWriterHealth reports the asynchronous request log writer as down
+// when writes are backing up or have failed within the last minute
+func RequestLogWriterHealth(ctx context.Context) error {
+	if pending := requestLogWriter.pending.Load(); pending > maxPendingRequestLogWrites {
+		return fmt.Errorf("%d request log writes pending", pending)
+	}
+
+	if lastFailure := requestLogWriter.lastFailure.Load(); lastFailure != 0 && time.Since(time.Unix(0, lastFailure)) < time.Minute {
+		return fmt.Errorf("request log write failed: %v", requestLogWriter.lastError.Load())
+	}
+	return nil
+}
+
+// DrainRequestLogWriter waits for pending request log writes to finish
+func DrainRequestLogWriter(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for requestLogWriter.pending.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d request log writes still pending: %w", requestLogWriter.pending.Load(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+type RequestLogPolicy struct {
+	// Fraction of requests to record, between 0 and 1
+	SampleRate float64
+	// Bodies larger than this are truncated, 0 disables body capture
+	MaxBodyBytes int
+	// Content type prefixes whose bodies are captured
+	ContentTypes []string
+	// Record requests that end with a 4xx or 5xx status even when they were
+	// not sampled. The status decides, not whether the handler returned an
+	// error, so equivalent responses are sampled the same way.
+	AlwaysLogErrors bool
+}
+
+type RequestLogConfig struct {
+	Default RequestLogPolicy
+	// Per-route overrides, keyed by path prefix, see policyForPath
+	Routes map[string]RequestLogPolicy
+}
+
+func DefaultRequestLogPolicy(config utils.Config) RequestLogPolicy {
+	return RequestLogPolicy{
+		SampleRate:      config.RequestLogSampleRate,
+		MaxBodyBytes:    config.RequestLogMaxBodyBytes,
+		ContentTypes:    utils.SplitList(config.RequestLogContentTypes),
+		AlwaysLogErrors: true,
+	}
+}
+
+func (policy RequestLogPolicy) capturesContentType(contentType string) bool {
+	if policy.MaxBodyBytes <= 0 || contentType == "" {
+		return false
+	}
+
+	contentType = strings.ToLower(contentType)
+	for _, allowed := range policy.ContentTypes {
+		if strings.HasPrefix(contentType, strings.ToLower(allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (policy RequestLogPolicy) truncate(body []byte) string {
+	if len(body) <= policy.MaxBodyBytes {
+		return string(body)
+	}
+	return string(body[:policy.MaxBodyBytes]) + requestlogs.TruncationMarker
+}
+
+func LogMiddleware(db *gorm.DB, config RequestLogConfig) fiber.Handler {
+	appVersion := buildinfo.Get().Version
+
 	return func(c *fiber.Ctx) error {
-		requestTime := time.Now()
+		policy := policyForPath(c.Path(), config.Default, config.Routes)
 
-		// Check if the Content-Type is for multipart form data
-		contentType := string(c.Request().Header.ContentType())
-		isMultipart := strings.HasPrefix(contentType, "multipart/form-data")
+		sampled := policy.SampleRate >= 1 || rand.Float64() < policy.SampleRate
+		if !sampled && !policy.AlwaysLogErrors {
+			return c.Next()
+		}
 
-		// Only read and log the body if it's not a multipart request
-		var bodyBytes []byte
-		if !isMultipart {
-			bodyBytes = c.Body()
+		requestTime := time.Now()
+
+		// Streamed request bodies are left for the handler to consume
+		var requestBody string
+		if !c.Request().IsBodyStream() && policy.capturesContentType(string(c.Request().Header.ContentType())) {
+			requestBody = policy.truncate(c.Body())
 		}
 
 		// Proceed with the actual request
-		c.Next()
-
-		// Capture the response set by the handler
-		responseBytes := c.Response().Body()
+		err := c.Next()
 
 		responseTime := time.Now()
 		duration := responseTime.Sub(requestTime).Seconds()
 
+		statusCode := ResponseStatus(c, err)
+		var errorMessage string
+		if err != nil {
+			errorMessage = err.Error()
+		}
+
+		failed := statusCode >= fiber.StatusBadRequest
+		if !sampled && !failed {
+			return err
+		}
+
+		// Failed requests are logged whether or not they were sampled, so
+		// only the rest stand in for the requests that were skipped
+		sampleWeight := 1.0
+		if policy.SampleRate < 1 && !(failed && policy.AlwaysLogErrors) {
+			sampleWeight = 1 / policy.SampleRate
+		}
+
+		// Reading the body of a streamed or file response would drain it, so
+		// only buffered responses are captured
+		var responseBody string
+		responseSize := c.Response().Header.ContentLength()
+		if !c.Response().IsBodyStream() {
+			responseBytes := c.Response().Body()
+			responseSize = len(responseBytes)
+			if policy.capturesContentType(string(c.Response().Header.ContentType())) {
+				responseBody = policy.truncate(responseBytes)
+			}
+		}
+		if responseSize < 0 {
+			responseSize = 0
+		}
+
+		// Without a Content-Length, buffered bodies are measured as received.
+		// A stream has been consumed by the handler, so its size is unknown.
+		requestSize := c.Request().Header.ContentLength()
+		if requestSize < 0 {
+			requestSize = 0
+			if !c.Request().IsBodyStream() {
+				requestSize = len(c.Request().Body())
+			}
+		}
+
 		// Safely attempt to get the current user
 		// If no user is found, set userID to nil
 		var userID *uuid.UUID
@@ -45,25 +186,51 @@ func LogMiddleware(db *gorm.DB) fiber.Handler {
 			userID = &contextUserID
 		}
 
+		var queries database.QueryStatsSnapshot
+		if stats := database.QueryStatsFrom(c.UserContext()); stats != nil {
+			queries = stats.Snapshot()
+		}
+
 		logEntry := models.RequestLog{
-			RequestTime:  requestTime,
-			ResponseTime: responseTime,
-			UserID:       userID,
-			Duration:     duration,
-			Method:       c.Method(),
-			Path:         c.Path(),
-			Headers:      fmt.Sprintf("%v", string(c.Request().Header.Header())),
-			Body:         string(bodyBytes),
-			Response:     string(responseBytes),
+			RequestID:       GetRequestID(c),
+			TraceID:         tracing.TraceID(c.UserContext()),
+			AppVersion:      appVersion,
+			RequestTime:     requestTime,
+			ResponseTime:    responseTime,
+			UserID:          userID,
+			Duration:        duration,
+			Method:          c.Method(),
+			Path:            c.Path(),
+			RouteTemplate:   c.Route().Path,
+			StatusCode:      statusCode,
+			ClientIP:        c.IP(),
+			UserAgent:       c.Get(fiber.HeaderUserAgent),
+			RequestSize:     requestSize,
+			ResponseSize:    responseSize,
+			Headers:         fmt.Sprintf("%v", string(c.Request().Header.Header())),
+			Body:            requestBody,
+			Response:        responseBody,
+			Error:           errorMessage,
+			QueryCount:      queries.Count,
+			QueryDuration:   queries.Duration.Seconds(),
+			SlowQueries:     queries.SlowCount,
+			RepeatedQueries: strings.Join(queries.RepeatedQueries, "\n"),
+			SampleWeight:    sampleWeight,
 		}
 
 		// Write the log to the database in a separate goroutine
+		ctx := c.UserContext()
+		requestLogWriter.pending.Add(1)
 		go func(db *gorm.DB, logEntry models.RequestLog) {
+			defer requestLogWriter.pending.Add(-1)
+
 			if err := db.Create(&logEntry).Error; err != nil {
-				log.Println("Error creating log entry:", err, logEntry.Path)
+				requestLogWriter.lastError.Store(err.Error())
+				requestLogWriter.lastFailure.Store(time.Now().UnixNano())
+				logger.ErrorContext(ctx, "Error creating log entry", "error", err, "path", logEntry.Path)
 			}
 		}(db, logEntry)
 
-		return c.Send(responseBytes)
+		return err
 	}
 }
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/bparsons094/go-server-base/models"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// capturingDB records the request logs the middleware writes instead of
+// sending them to a database
+func capturingDB(t *testing.T) (*gorm.DB, func() []models.RequestLog) {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, SkipDefaultTransaction: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var logs []models.RequestLog
+	err = db.Callback().Create().Before("gorm:create").Register("capture", func(tx *gorm.DB) {
+		mu.Lock()
+		defer mu.Unlock()
+		logs = append(logs, *tx.Statement.Dest.(*models.RequestLog))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db, func() []models.RequestLog {
+		if err := DrainRequestLogWriter(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return logs
+	}
+}
+
+func TestLogMiddlewareLogsFailuresByStatus(t *testing.T) {
+	db, captured := capturingDB(t)
+
+	app := fiber.New(fiber.Config{ErrorHandler: apierror.Handler})
+	app.Use(LogMiddleware(db, RequestLogConfig{Default: RequestLogPolicy{SampleRate: 0, AlwaysLogErrors: true}}))
+	app.Get("/ok", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/written", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"status": "fail"})
+	})
+	app.Get("/returned", func(c *fiber.Ctx) error {
+		return apierror.NotFound("Not found")
+	})
+
+	for _, path := range []string{"/ok", "/written", "/returned"} {
+		if _, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	logs := captured()
+	paths := map[string]models.RequestLog{}
+	for _, log := range logs {
+		paths[log.Path] = log
+	}
+	if len(logs) != 2 || paths["/written"].StatusCode != fiber.StatusNotFound || paths["/returned"].StatusCode != fiber.StatusNotFound {
+		t.Fatalf("logged %+v, want the two 404s and not the unsampled 200", paths)
+	}
+	for path, log := range paths {
+		if log.SampleWeight != 1 {
+			t.Errorf("%s: SampleWeight = %v, want 1 for a request that is always logged", path, log.SampleWeight)
+		}
+	}
+}
+
+func TestLogMiddlewareRequestSize(t *testing.T) {
+	db, captured := capturingDB(t)
+
+	app := fiber.New()
+	app.Use(LogMiddleware(db, RequestLogConfig{Default: RequestLogPolicy{SampleRate: 1, MaxBodyBytes: 4, ContentTypes: []string{"text/"}}}))
+	app.Post("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	request := httptest.NewRequest(fiber.MethodPost, "/", strings.NewReader("a body longer than the capture limit"))
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMETextPlain)
+	request.TransferEncoding = []string{"chunked"}
+	if _, err := app.Test(request); err != nil {
+		t.Fatal(err)
+	}
+
+	logs := captured()
+	if len(logs) != 1 {
+		t.Fatalf("logged %d requests, want 1", len(logs))
+	}
+	if want := len("a body longer than the capture limit"); logs[0].RequestSize != want {
+		t.Errorf("RequestSize = %d, want %d", logs[0].RequestSize, want)
+	}
+	if logs[0].SampleWeight != 1 {
+		t.Errorf("SampleWeight = %v, want 1 at a sample rate of 1", logs[0].SampleWeight)
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/bparsons094/go-server-base/metrics"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Metrics records request counts and latency labelled by route template, so
+// label cardinality stays bounded regardless of path parameters
+func Metrics(c *fiber.Ctx) error {
+	start := time.Now()
+
+	err := c.Next()
+
+	labels := []string{c.Method(), c.Route().Path, strconv.Itoa(ResponseStatus(c, err))}
+	metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+	metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+	return err
+}
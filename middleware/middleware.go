@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ResponseStatus returns the status the client will receive. The error handler
+// renders returned errors after the middleware chain unwinds, so the status
+// has to be derived from the error when there is one.
+func ResponseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+
+	return apierror.Status(err)
+}
+
+// policyForPath returns the override registered for the longest prefix of
+// path, or fallback when none matches. Prefixes match whole segments, so
+// "/internal" covers "/internal" and "/internal/debug" but not "/internalfoo".
+func policyForPath[T any](path string, fallback T, overrides map[string]T) T {
+	policy := fallback
+	matched := -1
+	for prefix, override := range overrides {
+		if hasPathPrefix(path, prefix) && len(prefix) > matched {
+			policy = override
+			matched = len(prefix)
+		}
+	}
+	return policy
+}
+
+func hasPathPrefix(path string, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
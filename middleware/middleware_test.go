@@ -0,0 +1,39 @@
+package middleware
+
+import "testing"
+
+func TestPolicyForPath(t *testing.T) {
+	overrides := map[string]string{
+		"/internal":       "internal",
+		"/internal/debug": "debug",
+		"/api/":           "api",
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", "default"},
+		{"/internal", "internal"},
+		{"/internal/", "internal"},
+		{"/internal/metrics", "internal"},
+		{"/internalfoo", "default"},
+		{"/internal/debug/pprof", "debug"},
+		{"/internal/debugger", "internal"},
+		{"/api", "api"},
+		{"/api/v1/users", "api"},
+		{"/apis", "default"},
+	}
+
+	for _, test := range tests {
+		if got := policyForPath(test.path, "default", overrides); got != test.want {
+			t.Errorf("policyForPath(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestPolicyForPathRootOverride(t *testing.T) {
+	if got := policyForPath("/anything", "default", map[string]string{"/": "root"}); got != "root" {
+		t.Errorf("a / override should match every path, got %q", got)
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/bparsons094/go-server-base/database"
+	"github.com/bparsons094/go-server-base/metrics"
+	"github.com/gofiber/fiber/v2"
+)
+
+// QueryStats collects counts and timings for the queries a request runs with
+// its user context. LogMiddleware stores them on the request log.
+func QueryStats(c *fiber.Ctx) error {
+	ctx, stats := database.WithQueryStats(c.UserContext())
+	c.SetUserContext(ctx)
+
+	err := c.Next()
+
+	metrics.HTTPRequestQueries.WithLabelValues(c.Method(), c.Route().Path).Observe(float64(stats.Snapshot().Count))
+
+	return err
+}
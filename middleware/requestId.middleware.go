@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"github.com/bparsons094/go-server-base/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+// RequestID accepts an incoming X-Request-ID or generates a new one, echoes it
+// on the response and makes it available through c.Locals("requestId") and the
+// user context so downstream handlers, GORM and the request log can share it.
+func RequestID(c *fiber.Ctx) error {
+	requestID := c.Get(RequestIDHeader)
+	if !isValidRequestID(requestID) {
+		requestID = uuid.NewString()
+	}
+
+	c.Set(RequestIDHeader, requestID)
+	c.Locals("requestId", requestID)
+	c.SetUserContext(utils.WithRequestID(c.UserContext(), requestID))
+
+	return c.Next()
+}
+
+func GetRequestID(c *fiber.Ctx) string {
+	requestID, _ := c.Locals("requestId").(string)
+	return requestID
+}
+
+// Only allow IDs that are safe to echo back in headers and write to logs
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, char := range requestID {
+		switch {
+		case char >= 'a' && char <= 'z':
+		case char >= 'A' && char <= 'Z':
+		case char >= '0' && char <= '9':
+		case char == '-' || char == '_' || char == '.' || char == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
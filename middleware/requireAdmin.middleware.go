@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/bparsons094/go-server-base/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireAdmin must run after AuthenticateUser
+func RequireAdmin(c *fiber.Ctx) error {
+	user, ok := c.Locals("currentUser").(models.User)
+	if !ok || !user.IsAdmin() {
+		return apierror.Forbidden("You do not have permission to access this resource")
+	}
+
+	return c.Next()
+}
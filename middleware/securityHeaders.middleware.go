@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bparsons094/go-server-base/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CSPNonce is replaced by a fresh 'nonce-...' source on every request. Read
+// the value for inline scripts and styles with GetCSPNonce.
+const CSPNonce = "{nonce}"
+
+const CSPReportPath = "/csp-reports"
+
+type cspDirective struct {
+	name    string
+	sources []string
+}
+
+// CSP builds a Content-Security-Policy, keeping directives in the order added
+type CSP struct {
+	directives []cspDirective
+}
+
+func NewCSP() *CSP {
+	return &CSP{}
+}
+
+// Directive sets name to sources, replacing an earlier value
+func (csp *CSP) Directive(name string, sources ...string) *CSP {
+	for i, directive := range csp.directives {
+		if directive.name == name {
+			csp.directives[i].sources = sources
+			return csp
+		}
+	}
+	csp.directives = append(csp.directives, cspDirective{name: name, sources: sources})
+	return csp
+}
+
+// Clone lets a route group start from another policy without changing it
+func (csp *CSP) Clone() *CSP {
+	clone := &CSP{directives: make([]cspDirective, len(csp.directives))}
+	for i, directive := range csp.directives {
+		clone.directives[i] = cspDirective{name: directive.name, sources: append([]string(nil), directive.sources...)}
+	}
+	return clone
+}
+
+func (csp *CSP) usesNonce() bool {
+	for _, directive := range csp.directives {
+		for _, source := range directive.sources {
+			if source == CSPNonce {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (csp *CSP) header(nonce string) string {
+	parts := make([]string, 0, len(csp.directives))
+	for _, directive := range csp.directives {
+		part := directive.name
+		for _, source := range directive.sources {
+			if source == CSPNonce {
+				source = "'nonce-" + nonce + "'"
+			}
+			part += " " + source
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
+
+type SecurityHeadersPolicy struct {
+	// Only sent over HTTPS, zero disables HSTS
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	CSP                   *CSP
+	// Reports violations without blocking, for trying out a new policy
+	CSPReportOnly             bool
+	FrameOptions              string
+	ReferrerPolicy            string
+	PermissionsPolicy         string
+	CrossOriginOpenerPolicy   string
+	CrossOriginEmbedderPolicy string
+	CrossOriginResourcePolicy string
+}
+
+type SecurityHeadersConfig struct {
+	Default SecurityHeadersPolicy
+	// Looser policies for the HTML pages, keyed by path prefix
+	Groups map[string]SecurityHeadersPolicy
+}
+
+// DefaultSecurityHeadersConfig locks the JSON API down completely and relaxes
+// the policy only for the few HTML pages the server renders
+func DefaultSecurityHeadersConfig(config utils.Config) SecurityHeadersConfig {
+	api := SecurityHeadersPolicy{
+		HSTSMaxAge:            config.HSTSMaxAge,
+		HSTSIncludeSubdomains: true,
+		CSP: NewCSP().
+			Directive("default-src", "'none'").
+			Directive("frame-ancestors", "'none'").
+			Directive("base-uri", "'none'").
+			Directive("form-action", "'none'").
+			Directive("report-uri", CSPReportPath),
+		CSPReportOnly:             config.CSPReportOnly,
+		FrameOptions:              "DENY",
+		ReferrerPolicy:            "no-referrer",
+		PermissionsPolicy:         "camera=(), microphone=(), geolocation=(), payment=(), usb=()",
+		CrossOriginOpenerPolicy:   "same-origin",
+		CrossOriginEmbedderPolicy: "require-corp",
+		CrossOriginResourcePolicy: "same-origin",
+	}
+
+	monitor := api
+	monitor.CrossOriginEmbedderPolicy = ""
+	monitor.CSP = api.CSP.Clone().
+		Directive("default-src", "'self'").
+		Directive("script-src", "'self'", "'unsafe-inline'", "https://cdn.jsdelivr.net").
+		Directive("style-src", "'self'", "'unsafe-inline'", "https://fonts.googleapis.com").
+		Directive("font-src", "'self'", "https://fonts.gstatic.com").
+		Directive("connect-src", "'self'")
+
+	// Swagger UI is served from the binary and started by an inline script
+	docs := api
+	docs.CrossOriginEmbedderPolicy = ""
+	docs.CSP = api.CSP.Clone().
+		Directive("default-src", "'self'").
+		Directive("script-src", "'self'", CSPNonce).
+		Directive("style-src", "'self'", "'unsafe-inline'").
+		Directive("img-src", "'self'", "data:").
+		Directive("connect-src", "'self'")
+
+	debug := api
+	debug.CSP = api.CSP.Clone().
+		Directive("default-src", "'self'").
+		Directive("style-src", "'self'", "'unsafe-inline'")
+
+	return SecurityHeadersConfig{
+		Default: api,
+		Groups: map[string]SecurityHeadersPolicy{
+			"/health/monitor": monitor,
+			"/internal/debug": debug,
+			"/api/docs":       docs,
+		},
+	}
+}
+
+func SecurityHeaders(config SecurityHeadersConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		policy := policyForPath(c.Path(), config.Default, config.Groups)
+
+		c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+		setIfNotEmpty(c, fiber.HeaderXFrameOptions, policy.FrameOptions)
+		setIfNotEmpty(c, fiber.HeaderReferrerPolicy, policy.ReferrerPolicy)
+		setIfNotEmpty(c, fiber.HeaderPermissionsPolicy, policy.PermissionsPolicy)
+		setIfNotEmpty(c, "Cross-Origin-Opener-Policy", policy.CrossOriginOpenerPolicy)
+		setIfNotEmpty(c, "Cross-Origin-Embedder-Policy", policy.CrossOriginEmbedderPolicy)
+		setIfNotEmpty(c, fiber.HeaderCrossOriginResourcePolicy, policy.CrossOriginResourcePolicy)
+
+		if policy.HSTSMaxAge > 0 && c.Protocol() == "https" {
+			hsts := "max-age=" + strconv.Itoa(int(policy.HSTSMaxAge.Seconds()))
+			if policy.HSTSIncludeSubdomains {
+				hsts += "; includeSubDomains"
+			}
+			if policy.HSTSPreload {
+				hsts += "; preload"
+			}
+			c.Set(fiber.HeaderStrictTransportSecurity, hsts)
+		}
+
+		if policy.CSP != nil {
+			var nonce string
+			if policy.CSP.usesNonce() {
+				nonce = newCSPNonce()
+				c.Locals("cspNonce", nonce)
+			}
+
+			header := fiber.HeaderContentSecurityPolicy
+			if policy.CSPReportOnly {
+				header = fiber.HeaderContentSecurityPolicyReportOnly
+			}
+			c.Set(header, policy.CSP.header(nonce))
+		}
+
+		return c.Next()
+	}
+}
+
+// GetCSPNonce returns this request's nonce, empty when the policy has none
+func GetCSPNonce(c *fiber.Ctx) string {
+	nonce, _ := c.Locals("cspNonce").(string)
+	return nonce
+}
+
+func newCSPNonce() string {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(nonce)
+}
+
+func setIfNotEmpty(c *fiber.Ctx, header, value string) {
+	if value != "" {
+		c.Set(header, value)
+	}
+}
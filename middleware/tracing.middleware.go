@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/bparsons094/go-server-base/tracing"
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request, continuing the trace from
+// incoming W3C traceparent headers. The span context is also stored in
+// c.Locals("spanContext") so websocket handlers can link back to it.
+func Tracing(c *fiber.Ctx) error {
+	carrier := propagation.HeaderCarrier(http.Header(c.GetReqHeaders()))
+	ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), carrier)
+
+	ctx, span := tracing.Tracer().Start(ctx, c.Method()+" "+c.Path(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String(string(semconv.HTTPRequestMethodKey), c.Method()),
+			semconv.URLPath(c.Path()),
+			semconv.ClientAddress(c.IP()),
+			semconv.UserAgentOriginal(c.Get(fiber.HeaderUserAgent)),
+			attribute.String("request.id", GetRequestID(c)),
+		),
+	)
+	defer span.End()
+
+	c.SetUserContext(ctx)
+	c.Locals("spanContext", span.SpanContext())
+
+	err := c.Next()
+
+	status := ResponseStatus(c, err)
+	route := c.Route().Path
+	span.SetName(c.Method() + " " + route)
+	span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(status))
+
+	if err != nil {
+		span.RecordError(err)
+	}
+	if status >= fiber.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+
+	return err
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		ID:          "20261019090000",
+		Description: "Add request_id to request_logs",
+		Migrate: func(tx *gorm.DB) error {
+			if err := tx.Exec("ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS request_id varchar(128)").Error; err != nil {
+				return err
+			}
+			return tx.Exec("CREATE INDEX IF NOT EXISTS idx_request_logs_request_id ON request_logs (request_id)").Error
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := tx.Exec("DROP INDEX IF EXISTS idx_request_logs_request_id").Error; err != nil {
+				return err
+			}
+			return tx.Exec("ALTER TABLE request_logs DROP COLUMN IF EXISTS request_id").Error
+		},
+	})
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		ID:          "20261019100000",
+		Description: "Add status code, client details, sizes, route template and error to request_logs",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS route_template varchar(255) NOT NULL DEFAULT ''",
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS status_code integer NOT NULL DEFAULT 0",
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS client_ip varchar(64) NOT NULL DEFAULT ''",
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS user_agent text NOT NULL DEFAULT ''",
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS request_size integer NOT NULL DEFAULT 0",
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS response_size integer NOT NULL DEFAULT 0",
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS error text NOT NULL DEFAULT ''",
+				// Error rate over time, overall and per route
+				"CREATE INDEX IF NOT EXISTS idx_request_logs_status_code_request_time ON request_logs (status_code, request_time)",
+				"CREATE INDEX IF NOT EXISTS idx_request_logs_route_template_request_time ON request_logs (route_template, request_time)",
+				"CREATE INDEX IF NOT EXISTS idx_request_logs_errors_request_time ON request_logs (request_time) WHERE status_code >= 500",
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP INDEX IF EXISTS idx_request_logs_errors_request_time",
+				"DROP INDEX IF EXISTS idx_request_logs_route_template_request_time",
+				"DROP INDEX IF EXISTS idx_request_logs_status_code_request_time",
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS error",
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS response_size",
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS request_size",
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS user_agent",
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS client_ip",
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS status_code",
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS route_template",
+			)
+		},
+	})
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		ID:          "20261019110000",
+		Description: "Add role to users",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE users ADD COLUMN IF NOT EXISTS role varchar(50) NOT NULL DEFAULT 'user'",
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE users DROP COLUMN IF EXISTS role",
+			)
+		},
+	})
+}
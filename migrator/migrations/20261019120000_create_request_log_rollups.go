@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		ID:          "20261019120000",
+		Description: "Create hourly and daily request log rollups and their watermarks",
+		Migrate: func(tx *gorm.DB) error {
+			statements := []string{}
+			for _, table := range []string{"request_log_hourly_rollups", "request_log_daily_rollups"} {
+				statements = append(statements,
+					`CREATE TABLE IF NOT EXISTS `+table+` (
+						bucket_start timestamptz NOT NULL,
+						dimension varchar(20) NOT NULL,
+						dimension_value varchar(512) NOT NULL,
+						request_count bigint NOT NULL DEFAULT 0,
+						error_count bigint NOT NULL DEFAULT 0,
+						client_error_count bigint NOT NULL DEFAULT 0,
+						p50_ms double precision NOT NULL DEFAULT 0,
+						p95_ms double precision NOT NULL DEFAULT 0,
+						p99_ms double precision NOT NULL DEFAULT 0,
+						updated_at timestamptz,
+						PRIMARY KEY (bucket_start, dimension, dimension_value)
+					)`,
+					"CREATE INDEX IF NOT EXISTS idx_"+table+"_dimension_value ON "+table+" (dimension, dimension_value, bucket_start)",
+				)
+			}
+			statements = append(statements, `CREATE TABLE IF NOT EXISTS rollup_watermarks (
+				name varchar(50) PRIMARY KEY,
+				processed_until timestamptz NOT NULL,
+				updated_at timestamptz
+			)`)
+
+			return execAll(tx, statements...)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS rollup_watermarks",
+				"DROP TABLE IF EXISTS request_log_daily_rollups",
+				"DROP TABLE IF EXISTS request_log_hourly_rollups",
+			)
+		},
+	})
+}
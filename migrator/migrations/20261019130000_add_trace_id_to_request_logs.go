@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		ID:          "20261019130000",
+		Description: "Add trace_id to request_logs",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS trace_id varchar(32)",
+				"CREATE INDEX IF NOT EXISTS idx_request_logs_trace_id ON request_logs (trace_id)",
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP INDEX IF EXISTS idx_request_logs_trace_id",
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS trace_id",
+			)
+		},
+	})
+}
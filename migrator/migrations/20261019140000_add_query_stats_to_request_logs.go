@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		ID:          "20261019140000",
+		Description: "Add query statistics to request_logs",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS query_count integer NOT NULL DEFAULT 0",
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS query_duration double precision NOT NULL DEFAULT 0",
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS slow_queries integer NOT NULL DEFAULT 0",
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS repeated_queries text NOT NULL DEFAULT ''",
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS repeated_queries",
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS slow_queries",
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS query_duration",
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS query_count",
+			)
+		},
+	})
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		ID:          "20261019150000",
+		Description: "Add app_version to request_logs",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS app_version varchar(64) NOT NULL DEFAULT ''",
+				"CREATE INDEX IF NOT EXISTS idx_request_logs_app_version ON request_logs (app_version)",
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP INDEX IF EXISTS idx_request_logs_app_version",
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS app_version",
+			)
+		},
+	})
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		ID:          "20261019160000",
+		Description: "Create rate_limit_buckets",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+					key varchar(255) PRIMARY KEY,
+					count double precision NOT NULL DEFAULT 0,
+					previous double precision NOT NULL DEFAULT 0,
+					start timestamptz NOT NULL,
+					expires_at timestamptz NOT NULL
+				)`,
+				"CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_expires_at ON rate_limit_buckets (expires_at)",
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return execAll(tx, "DROP TABLE IF EXISTS rate_limit_buckets")
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		ID:          "20261019170000",
+		Description: "Create csp_reports",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS csp_reports (
+					id bigserial PRIMARY KEY,
+					created_at timestamptz,
+					document_uri text NOT NULL DEFAULT '',
+					effective_directive varchar(255) NOT NULL DEFAULT '',
+					blocked_uri text NOT NULL DEFAULT '',
+					source_file text NOT NULL DEFAULT '',
+					line_number bigint NOT NULL DEFAULT 0,
+					disposition varchar(20) NOT NULL DEFAULT '',
+					client_ip varchar(64) NOT NULL DEFAULT '',
+					user_agent text NOT NULL DEFAULT '',
+					raw text NOT NULL
+				)`,
+				"CREATE INDEX IF NOT EXISTS idx_csp_reports_created_at ON csp_reports (created_at)",
+				"CREATE INDEX IF NOT EXISTS idx_csp_reports_effective_directive ON csp_reports (effective_directive)",
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return execAll(tx, "DROP TABLE IF EXISTS csp_reports")
+		},
+	})
+}
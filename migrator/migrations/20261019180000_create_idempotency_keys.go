@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		ID:          "20261019180000",
+		Description: "Create idempotency_keys",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				`CREATE TABLE IF NOT EXISTS idempotency_keys (
+					scope varchar(255) NOT NULL,
+					key varchar(255) NOT NULL,
+					fingerprint varchar(64) NOT NULL,
+					status_code bigint NOT NULL DEFAULT 0,
+					headers jsonb,
+					body bytea,
+					locked_until timestamptz NOT NULL,
+					created_at timestamptz,
+					expires_at timestamptz NOT NULL,
+					PRIMARY KEY (scope, key)
+				)`,
+				"CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at)",
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return execAll(tx, "DROP TABLE IF EXISTS idempotency_keys")
+		},
+	})
+}
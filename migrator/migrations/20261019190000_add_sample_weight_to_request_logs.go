@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterMigration(Migration{
+		ID:          "20261019190000",
+		Description: "Add sample_weight to request_logs",
+		Migrate: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE request_logs ADD COLUMN IF NOT EXISTS sample_weight double precision NOT NULL DEFAULT 1",
+			)
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE request_logs DROP COLUMN IF EXISTS sample_weight",
+			)
+		},
+	})
+}
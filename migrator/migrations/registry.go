@@ -1,6 +1,8 @@
 package migrations
 
 import (
+	"sort"
+
 	"github.com/go-gormigrate/gormigrate/v2"
 	"gorm.io/gorm"
 )
@@ -12,6 +14,9 @@ type Migration struct {
 	Rollback    func(tx *gorm.DB) error
 }
 
+// TableName is where gormigrate records applied migrations
+const TableName = "migrations"
+
 var RegisteredMigrations []*gormigrate.Migration
 
 func RegisterMigration(migration Migration) {
@@ -22,3 +27,37 @@ func RegisterMigration(migration Migration) {
 	}
 	RegisteredMigrations = append(RegisteredMigrations, gorMigration)
 }
+
+// Pending returns the IDs of registered migrations that have not been applied
+func Pending(db *gorm.DB) ([]string, error) {
+	var applied []string
+	if db.Migrator().HasTable(TableName) {
+		if err := db.Table(TableName).Pluck("id", &applied).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	appliedIDs := make(map[string]bool, len(applied))
+	for _, id := range applied {
+		appliedIDs[id] = true
+	}
+
+	var pending []string
+	for _, migration := range RegisteredMigrations {
+		if !appliedIDs[migration.ID] {
+			pending = append(pending, migration.ID)
+		}
+	}
+	sort.Strings(pending)
+	return pending, nil
+}
+
+// execAll runs raw SQL statements in order, stopping at the first error
+func execAll(tx *gorm.DB, statements ...string) error {
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
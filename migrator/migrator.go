@@ -2,13 +2,13 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/bparsons094/go-server-base/database"
+	"github.com/bparsons094/go-server-base/logging"
 	"github.com/bparsons094/go-server-base/migrator/migrations"
 	"github.com/bparsons094/go-server-base/utils"
 	"github.com/go-gormigrate/gormigrate/v2"
@@ -18,10 +18,11 @@ import (
 var DB *gorm.DB
 var Config utils.Config
 
-func init() {
-	log.SetOutput(os.Stdout)
+var logger = logging.For("migrator")
 
+func init() {
 	Config = utils.LoadConfig("./")
+	logging.Init(Config)
 
 	DB = database.ConnectDB(Config)
 }
@@ -32,34 +33,34 @@ func main() {
 	for i, arg := range args[1:] {
 		switch {
 		case arg == "-up":
-			log.Println("Running Up")
+			logger.Info("Running Up")
 			migrateUp()
-			log.Println("All Migrations Completed")
+			logger.Info("All Migrations Completed")
 		case arg == "-down":
-			log.Println("Running Down")
+			logger.Info("Running Down")
 			migrateDown()
-			log.Println("All Migrations Completed")
+			logger.Info("All Migrations Completed")
 		case strings.HasPrefix(arg, "-create"):
 			// If -create is provided, expect the next argument to be the migration name
 			if len(args) <= i+1 {
-				log.Fatal("Expected migration name after -create")
+				logging.Fatal(logger, "Expected migration name after -create")
 			}
 
 			if len(args) > i+2 { // i+2 because the arguments slice includes the program name and we're 1-indexed here
 				migrationName := args[i+2]
 				err := createMigrationFile(migrationName)
 				if err != nil {
-					log.Fatalf("Error creating migration file: %v", err)
+					logging.Fatal(logger, "Error creating migration file", "error", err)
 				}
 			} else {
-				log.Fatal("Expected migration name after -create")
+				logging.Fatal(logger, "Expected migration name after -create")
 			}
 		}
 	}
 }
 
 var MigrationOptions = &gormigrate.Options{
-	TableName:                 "migrations",
+	TableName:                 migrations.TableName,
 	IDColumnName:              "id",
 	IDColumnSize:              255,
 	UseTransaction:            true,
@@ -68,7 +69,7 @@ var MigrationOptions = &gormigrate.Options{
 
 func migrateUp() {
 	if len(migrations.RegisteredMigrations) == 0 {
-		log.Println("No migrations registered")
+		logger.Info("No migrations registered")
 		return
 	}
 
@@ -79,13 +80,13 @@ func migrateUp() {
 	m := gormigrate.New(DB, MigrationOptions, migrations.RegisteredMigrations)
 
 	if err := m.Migrate(); err != nil {
-		log.Fatalf("Migration failed: %v", err)
+		logging.Fatal(logger, "Migration failed", "error", err)
 	}
 }
 
 func migrateDown() {
 	if len(migrations.RegisteredMigrations) == 0 {
-		log.Println("No migrations registered")
+		logger.Info("No migrations registered")
 		return
 	}
 
@@ -97,7 +98,7 @@ func migrateDown() {
 	m := gormigrate.New(DB, MigrationOptions, migrations.RegisteredMigrations)
 
 	if err := m.RollbackLast(); err != nil {
-		log.Fatalf("Migration failed: %v", err)
+		logging.Fatal(logger, "Migration failed", "error", err)
 	}
 }
 
@@ -148,6 +149,6 @@ func init() {
 		return err
 	}
 
-	log.Printf("Migration file created: %s", filePath)
+	logger.Info("Migration file created", "path", filePath)
 	return nil
 }
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+type CSPReport struct {
+	ID                 int       `gorm:"primaryKey" json:"id"`
+	CreatedAt          time.Time `gorm:"autoCreateTime;index" json:"createdAt"`
+	DocumentURI        string    `gorm:"type:text;not null;default:''" json:"documentUri"`
+	EffectiveDirective string    `gorm:"type:varchar(255);not null;default:'';index" json:"effectiveDirective"`
+	BlockedURI         string    `gorm:"type:text;not null;default:''" json:"blockedUri"`
+	SourceFile         string    `gorm:"type:text;not null;default:''" json:"sourceFile"`
+	LineNumber         int       `gorm:"not null;default:0" json:"lineNumber"`
+	Disposition        string    `gorm:"type:varchar(20);not null;default:''" json:"disposition"`
+	ClientIP           string    `gorm:"type:varchar(64);not null;default:''" json:"clientIp"`
+	UserAgent          string    `gorm:"type:text;not null;default:''" json:"userAgent"`
+	Raw                string    `gorm:"type:text;not null" json:"raw"`
+}
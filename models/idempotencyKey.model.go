@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyKey stores the first response to a request sent with an
+// Idempotency-Key header. StatusCode stays 0 while the request is running.
+type IdempotencyKey struct {
+	Scope       string    `gorm:"primaryKey;type:varchar(255)" json:"scope"`
+	Key         string    `gorm:"primaryKey;type:varchar(255)" json:"key"`
+	Fingerprint string    `gorm:"type:varchar(64);not null" json:"fingerprint"`
+	StatusCode  int       `gorm:"not null;default:0" json:"statusCode"`
+	Headers     JSONB     `gorm:"type:jsonb" json:"headers"`
+	Body        []byte    `gorm:"type:bytea" json:"-"`
+	LockedUntil time.Time `gorm:"not null" json:"lockedUntil"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	ExpiresAt   time.Time `gorm:"not null;index" json:"expiresAt"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// RateLimitBucket holds one key's limiter state when limits are shared
+// across replicas. Count is the current window's count or the tokens left.
+type RateLimitBucket struct {
+	Key       string    `gorm:"primaryKey;type:varchar(255)" json:"key"`
+	Count     float64   `gorm:"not null;default:0" json:"count"`
+	Previous  float64   `gorm:"not null;default:0" json:"previous"`
+	Start     time.Time `gorm:"not null" json:"start"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expiresAt"`
+}
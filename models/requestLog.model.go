@@ -7,15 +7,32 @@ import (
 )
 
 type RequestLog struct {
-	ID           int        `gorm:"primaryKey" json:"id"`
-	RequestTime  time.Time  `gorm:"index" json:"requestTime"`
-	ResponseTime time.Time  `gorm:"index" json:"responseTime"`
-	UserID       *uuid.UUID `gorm:"type:uuid;index" json:"userId"`
-	User         User       `gorm:"foreignKey:UserID;references:ID" json:"user"`
-	Duration     float64    `gorm:"index" json:"duration"`
-	Method       string     `gorm:"type:varchar(255);not null" json:"method"`
-	Path         string     `gorm:"type:varchar(255);not null" json:"path"`
-	Headers      string     `gorm:"type:text;not null" json:"headers"`
-	Body         string     `gorm:"type:text;not null" json:"body"`
-	Response     string     `gorm:"type:text;not null" json:"response"`
+	ID              int        `gorm:"primaryKey" json:"id"`
+	RequestID       string     `gorm:"type:varchar(128);index" json:"requestId"`
+	TraceID         string     `gorm:"type:varchar(32);index" json:"traceId"`
+	AppVersion      string     `gorm:"type:varchar(64);not null;default:'';index" json:"appVersion"`
+	RequestTime     time.Time  `gorm:"index;index:idx_request_logs_status_code_request_time,priority:2;index:idx_request_logs_route_template_request_time,priority:2" json:"requestTime"`
+	ResponseTime    time.Time  `gorm:"index" json:"responseTime"`
+	UserID          *uuid.UUID `gorm:"type:uuid;index" json:"userId"`
+	User            User       `gorm:"foreignKey:UserID;references:ID" json:"user"`
+	Duration        float64    `gorm:"index" json:"duration"`
+	Method          string     `gorm:"type:varchar(255);not null" json:"method"`
+	Path            string     `gorm:"type:varchar(255);not null" json:"path"`
+	RouteTemplate   string     `gorm:"type:varchar(255);not null;default:'';index:idx_request_logs_route_template_request_time,priority:1" json:"routeTemplate"`
+	StatusCode      int        `gorm:"not null;default:0;index:idx_request_logs_status_code_request_time,priority:1" json:"statusCode"`
+	ClientIP        string     `gorm:"type:varchar(64);not null;default:''" json:"clientIp"`
+	UserAgent       string     `gorm:"type:text;not null;default:''" json:"userAgent"`
+	RequestSize     int        `gorm:"not null;default:0" json:"requestSize"`
+	ResponseSize    int        `gorm:"not null;default:0" json:"responseSize"`
+	Headers         string     `gorm:"type:text;not null" json:"headers"`
+	Body            string     `gorm:"type:text;not null" json:"body"`
+	Response        string     `gorm:"type:text;not null" json:"response"`
+	Error           string     `gorm:"type:text;not null;default:''" json:"error"`
+	QueryCount      int        `gorm:"not null;default:0" json:"queryCount"`
+	QueryDuration   float64    `gorm:"not null;default:0" json:"queryDuration"`
+	SlowQueries     int        `gorm:"not null;default:0" json:"slowQueries"`
+	RepeatedQueries string     `gorm:"type:text;not null;default:''" json:"repeatedQueries"`
+	// How many requests the row stands for: 1/sample rate when it was logged
+	// by sampling, 1 when it would have been logged regardless
+	SampleWeight float64 `gorm:"not null;default:1" json:"sampleWeight"`
 }
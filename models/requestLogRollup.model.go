@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	RollupDimensionRoute = "route"
+	RollupDimensionUser  = "user"
+)
+
+// RequestLogRollup aggregates request_logs for one bucket and one dimension
+// value, either "METHOD /route/template" or a user ID. Latencies are in ms.
+// Sampled rows are weighted up, so counts estimate all traffic.
+type RequestLogRollup struct {
+	BucketStart      time.Time `gorm:"primaryKey" json:"bucketStart"`
+	Dimension        string    `gorm:"primaryKey;type:varchar(20)" json:"dimension"`
+	DimensionValue   string    `gorm:"primaryKey;type:varchar(512)" json:"dimensionValue"`
+	RequestCount     int64     `gorm:"not null;default:0" json:"requestCount"`
+	ErrorCount       int64     `gorm:"not null;default:0" json:"errorCount"`
+	ClientErrorCount int64     `gorm:"not null;default:0" json:"clientErrorCount"`
+	P50Ms            float64   `gorm:"not null;default:0" json:"p50Ms"`
+	P95Ms            float64   `gorm:"not null;default:0" json:"p95Ms"`
+	P99Ms            float64   `gorm:"not null;default:0" json:"p99Ms"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+type HourlyRequestRollup struct {
+	RequestLogRollup
+}
+
+func (HourlyRequestRollup) TableName() string {
+	return "request_log_hourly_rollups"
+}
+
+type DailyRequestRollup struct {
+	RequestLogRollup
+}
+
+func (DailyRequestRollup) TableName() string {
+	return "request_log_daily_rollups"
+}
+
+// RollupWatermark records how far a rollup has been computed. Buckets from
+// ProcessedUntil onwards are recomputed on the next run.
+type RollupWatermark struct {
+	Name           string    `gorm:"primaryKey;type:varchar(50)" json:"name"`
+	ProcessedUntil time.Time `gorm:"not null" json:"processedUntil"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
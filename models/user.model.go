@@ -16,4 +16,14 @@ type User struct {
 	Email     string `gorm:"type:varchar(255);not null;unique" json:"email"`
 	Username  string `gorm:"type:varchar(255);not null;unique" json:"username"`
 	Password  string `gorm:"type:varchar(255);not null" json:"-"`
+	Role      string `gorm:"type:varchar(50);not null;default:'user'" json:"role"`
+}
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+func (u User) IsAdmin() bool {
+	return u.Role == RoleAdmin
 }
@@ -0,0 +1,269 @@
+// Package openapi builds an OpenAPI 3.1 document from annotated routes.
+// Routes are registered through Route with an Operation naming the Go types
+// they read and return; Build reflects over those types once all routes are
+// registered. Struct fields are described by their json, query and validate
+// tags, plus an optional doc tag for a description.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Operation documents one route. Query is a struct whose query tags are the
+// route's query parameters, Request the JSON body and Response the JSON body
+// of a successful response. Each may be left nil.
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Query       any
+	Request     any
+	Response    any
+	// Status of a successful response, 200 when zero
+	Status int
+	// ContentType of a successful response, application/json when empty
+	ContentType string
+	Deprecated  bool
+}
+
+type Info struct {
+	Title       string
+	Description string
+	Version     string
+}
+
+type registeredOperation struct {
+	method    string
+	path      string
+	operation Operation
+}
+
+var (
+	mu         sync.Mutex
+	operations []registeredOperation
+	document   atomic.Pointer[[]byte]
+)
+
+// Route registers handlers on router like router.Add and records the route's
+// documentation under its full path
+func Route(router fiber.Router, method string, path string, operation Operation, handlers ...fiber.Handler) {
+	router.Add(method, path, handlers...)
+	Register(method, prefixOf(router)+path, operation)
+}
+
+// Register documents a route registered some other way. path uses fiber's
+// syntax, e.g. /api/v1/users/:id.
+func Register(method string, path string, operation Operation) {
+	mu.Lock()
+	defer mu.Unlock()
+	operations = append(operations, registeredOperation{method: method, path: path, operation: operation})
+}
+
+// Build renders the document from every registered route. It is meant to run
+// once at startup, after the routes are set up, and is what Handler serves.
+func Build(info Info) error {
+	mu.Lock()
+	registered := append([]registeredOperation(nil), operations...)
+	mu.Unlock()
+
+	sort.SliceStable(registered, func(i, j int) bool {
+		return registered[i].path < registered[j].path
+	})
+
+	builder := newSchemaBuilder()
+	errorSchema := builder.schemaFor(reflect.TypeOf(apierror.Envelope{}))
+
+	paths := map[string]schema{}
+	for _, route := range registered {
+		path, pathParameters := convertPath(route.path)
+		if paths[path] == nil {
+			paths[path] = schema{}
+		}
+
+		method := strings.ToLower(route.method)
+		if _, exists := paths[path][method]; exists {
+			return fmt.Errorf("openapi: %s %s is documented twice", route.method, route.path)
+		}
+		paths[path][method] = operationSchema(builder, route, pathParameters)
+	}
+
+	spec := schema{
+		"openapi":           "3.1.0",
+		"jsonSchemaDialect": "https://spec.openapis.org/oas/3.1/dialect/base",
+		"info": schema{
+			"title":       info.Title,
+			"description": info.Description,
+			"version":     info.Version,
+		},
+		"paths": paths,
+		"components": schema{
+			"schemas": builder.components,
+			"responses": schema{
+				"Error": schema{
+					"description": "Error envelope, see apierror.Envelope",
+					"content":     schema{fiber.MIMEApplicationJSON: schema{"schema": errorSchema}},
+				},
+			},
+			"securitySchemes": schema{
+				"bearerAuth": schema{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		// Everything under /api requires a logged in user
+		"security": []schema{{"bearerAuth": []string{}}},
+	}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("openapi: %w", err)
+	}
+
+	document.Store(&data)
+	return nil
+}
+
+// Handler serves the document built by Build
+func Handler(c *fiber.Ctx) error {
+	data := document.Load()
+	if data == nil {
+		return apierror.New(fiber.StatusServiceUnavailable, "service_unavailable", "API document has not been built")
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(*data)
+}
+
+func operationSchema(builder *schemaBuilder, route registeredOperation, pathParameters []schema) schema {
+	operation := route.operation
+
+	result := schema{
+		"operationId": operationID(route.method, route.path),
+		"responses":   schema{"default": schema{"$ref": "#/components/responses/Error"}},
+	}
+	if operation.Summary != "" {
+		result["summary"] = operation.Summary
+	}
+	if operation.Description != "" {
+		result["description"] = operation.Description
+	}
+	if len(operation.Tags) > 0 {
+		result["tags"] = operation.Tags
+	}
+	if operation.Deprecated {
+		result["deprecated"] = true
+	}
+
+	parameters := append([]schema(nil), pathParameters...)
+	if operation.Query != nil {
+		parameters = append(parameters, queryParameters(builder, reflect.TypeOf(operation.Query))...)
+	}
+	if len(parameters) > 0 {
+		result["parameters"] = parameters
+	}
+
+	if operation.Request != nil {
+		result["requestBody"] = schema{
+			"required": true,
+			"content":  schema{fiber.MIMEApplicationJSON: schema{"schema": builder.schemaFor(reflect.TypeOf(operation.Request))}},
+		}
+	}
+
+	status := operation.Status
+	if status == 0 {
+		status = fiber.StatusOK
+	}
+	success := schema{"description": http.StatusText(status)}
+	if operation.Response != nil {
+		contentType := operation.ContentType
+		if contentType == "" {
+			contentType = fiber.MIMEApplicationJSON
+		}
+		success["content"] = schema{contentType: schema{"schema": builder.schemaFor(reflect.TypeOf(operation.Response))}}
+	}
+	result["responses"].(schema)[fmt.Sprint(status)] = success
+
+	return result
+}
+
+func queryParameters(builder *schemaBuilder, t reflect.Type) []schema {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var parameters []schema
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("query"), ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			parameters = append(parameters, queryParameters(builder, field.Type)...)
+			continue
+		}
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		fieldSchema := builder.fieldSchema(field)
+		parameter := schema{"name": name, "in": "query", "schema": fieldSchema}
+		if isRequired(field) {
+			parameter["required"] = true
+		}
+		if description, ok := fieldSchema["description"]; ok {
+			parameter["description"] = description
+			delete(fieldSchema, "description")
+		}
+		parameters = append(parameters, parameter)
+	}
+	return parameters
+}
+
+// convertPath turns fiber's /users/:id into OpenAPI's /users/{id} and
+// describes the parameters. Optional and wildcard segments are documented
+// as required strings, since OpenAPI has no optional path parameters.
+func convertPath(path string) (string, []schema) {
+	var parameters []schema
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		var name string
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			name = strings.TrimSuffix(strings.TrimPrefix(segment, ":"), "?")
+		case segment == "*" || segment == "+":
+			name = "wildcard"
+		default:
+			continue
+		}
+
+		segments[i] = "{" + name + "}"
+		parameters = append(parameters, schema{"name": name, "in": "path", "required": true, "schema": schema{"type": "string"}})
+	}
+	return strings.Join(segments, "/"), parameters
+}
+
+// operationID names an operation after its method and path, e.g.
+// GET /api/v1/users/:id becomes getApiV1UsersId
+func operationID(method string, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	}) {
+		id.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return id.String()
+}
+
+func prefixOf(router fiber.Router) string {
+	if group, ok := router.(*fiber.Group); ok {
+		return group.Prefix
+	}
+	return ""
+}
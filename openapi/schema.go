@@ -0,0 +1,239 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type schema = map[string]any
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	uuidType       = reflect.TypeOf(uuid.UUID{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	byteSliceType  = reflect.TypeOf([]byte(nil))
+)
+
+var unsafeSchemaName = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// schemaBuilder turns Go types into JSON Schema, the dialect OpenAPI 3.1
+// uses. Named structs become components and are referenced by $ref, so
+// recursive types terminate.
+type schemaBuilder struct {
+	components map[string]schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{components: map[string]schema{}, names: map[reflect.Type]string{}}
+}
+
+func (b *schemaBuilder) schemaFor(t reflect.Type) schema {
+	switch t {
+	case timeType:
+		return schema{"type": "string", "format": "date-time"}
+	case durationType:
+		return schema{"type": "integer", "format": "int64", "description": "Duration in nanoseconds"}
+	case uuidType:
+		return schema{"type": "string", "format": "uuid"}
+	case rawMessageType:
+		return schema{}
+	case byteSliceType:
+		return schema{"type": "string", "contentEncoding": "base64"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(b.schemaFor(t.Elem()))
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return schema{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return schema{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return schema{"type": "array", "items": b.schemaFor(t.Elem())}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": b.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		return schema{"$ref": "#/components/schemas/" + b.component(t)}
+	}
+
+	// Interfaces, and anything else encoding/json decides at runtime
+	return schema{}
+}
+
+// component registers a named struct once and returns its component name.
+// The placeholder is stored first so a type that refers to itself resolves
+// to the $ref instead of recursing.
+func (b *schemaBuilder) component(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+
+	name := unsafeSchemaName.ReplaceAllString(t.Name(), "_")
+	if _, taken := b.components[name]; taken {
+		name = unsafeSchemaName.ReplaceAllString(t.PkgPath()+"."+t.Name(), "_")
+	}
+
+	b.names[t] = name
+	b.components[name] = schema{}
+	b.components[name] = b.structSchema(t)
+	return name
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) schema {
+	properties := schema{}
+	var required []string
+	b.addFields(t, properties, &required)
+
+	result := schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		result["required"] = required
+	}
+	return result
+}
+
+// addFields follows encoding/json: unexported and "-" fields are skipped, and
+// untagged embedded structs are flattened into the parent
+func (b *schemaBuilder) addFields(t reflect.Type, properties schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.addFields(embedded, properties, required)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = b.fieldSchema(field)
+		if isRequired(field) {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// fieldSchema adds what the field's validate tag says about it to its type's
+// schema. Component references are left untouched, since JSON Schema would
+// apply the keywords to every use of the component.
+func (b *schemaBuilder) fieldSchema(field reflect.StructField) schema {
+	result := b.schemaFor(field.Type)
+	if _, isRef := result["$ref"]; isRef {
+		return result
+	}
+
+	if description := field.Tag.Get("doc"); description != "" {
+		result["description"] = description
+	}
+
+	kind := field.Type.Kind()
+	if kind == reflect.Pointer {
+		kind = field.Type.Elem().Kind()
+	}
+
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "uuid", "uuid4", "uuid_rfc4122", "uuid4_rfc4122":
+			result["format"] = "uuid"
+		case "email":
+			result["format"] = "email"
+		case "datetime":
+			if param == time.RFC3339 {
+				result["format"] = "date-time"
+			}
+		case "url", "http_url":
+			result["format"] = "uri"
+		case "oneof":
+			result["enum"] = enumValues(param, kind)
+		case "min", "gte":
+			setBound(result, kind, param, "minLength", "minItems", "minimum")
+		case "max", "lte":
+			setBound(result, kind, param, "maxLength", "maxItems", "maximum")
+		case "len":
+			setBound(result, kind, param, "minLength", "minItems", "minimum")
+			setBound(result, kind, param, "maxLength", "maxItems", "maximum")
+		}
+	}
+
+	return result
+}
+
+func isRequired(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+func setBound(result schema, kind reflect.Kind, param string, stringKey string, arrayKey string, numberKey string) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	switch kind {
+	case reflect.String:
+		result[stringKey] = int(value)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		result[arrayKey] = int(value)
+	default:
+		result[numberKey] = value
+	}
+}
+
+func enumValues(param string, kind reflect.Kind) []any {
+	var values []any
+	for _, value := range strings.Fields(param) {
+		if kind != reflect.String {
+			if number, err := strconv.ParseFloat(value, 64); err == nil {
+				values = append(values, number)
+				continue
+			}
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// nullable allows null alongside the schema. OpenAPI 3.1 dropped the
+// nullable keyword in favour of JSON Schema's type arrays.
+func nullable(inner schema) schema {
+	if typeName, ok := inner["type"].(string); ok {
+		inner["type"] = []string{typeName, "null"}
+		return inner
+	}
+	return schema{"anyOf": []schema{inner, {"type": "null"}}}
+}
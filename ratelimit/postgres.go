@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/bparsons094/go-server-base/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStore shares limits across replicas through rate_limit_buckets.
+// Each take locks the key's row, so concurrent requests are serialized.
+type PostgresStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	var result Result
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Make sure the row exists so there is always something to lock
+		bucket := models.RateLimitBucket{Key: key, Start: time.Time{}, ExpiresAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&bucket).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&bucket).Error; err != nil {
+			return err
+		}
+
+		var state State
+		if now.Before(bucket.ExpiresAt) {
+			state = State{Count: bucket.Count, Previous: bucket.Previous, Start: bucket.Start}
+		}
+
+		state, result = policy.Apply(state, now)
+
+		return tx.Model(&models.RateLimitBucket{}).Where("key = ?", key).Updates(map[string]interface{}{
+			"count":      state.Count,
+			"previous":   state.Previous,
+			"start":      state.Start,
+			"expires_at": now.Add(policy.Expiry()),
+		}).Error
+	})
+
+	return result, err
+}
+
+// DeleteExpired removes buckets no request has touched within their expiry
+func (s *PostgresStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.RateLimitBucket{})
+	return result.RowsAffected, result.Error
+}
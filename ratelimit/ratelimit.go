@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// SlidingWindow weights the previous window's count by how much of it
+	// still overlaps, which smooths the burst at fixed window boundaries
+	SlidingWindow = "sliding_window"
+	// TokenBucket allows bursts up to Limit and refills Limit per Window
+	TokenBucket = "token_bucket"
+)
+
+type Policy struct {
+	Algorithm string
+	Limit     int
+	Window    time.Duration
+}
+
+func (p Policy) Validate() error {
+	if p.Algorithm != SlidingWindow && p.Algorithm != TokenBucket {
+		return fmt.Errorf("unsupported rate limit algorithm %q, expected %s or %s", p.Algorithm, SlidingWindow, TokenBucket)
+	}
+	if p.Limit <= 0 || p.Window <= 0 {
+		return fmt.Errorf("rate limit needs a positive limit and window")
+	}
+	return nil
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Until the limit fully resets
+	Reset time.Duration
+	// Until the next request would be allowed, zero when allowed
+	RetryAfter time.Duration
+}
+
+// State is what a Store keeps per key. Count is the current window's count
+// for SlidingWindow and the tokens left for TokenBucket.
+type State struct {
+	Count    float64
+	Previous float64
+	Start    time.Time
+}
+
+// Expiry is how long a store must keep state after it was last updated
+func (p Policy) Expiry() time.Duration {
+	return 2 * p.Window
+}
+
+type Store interface {
+	// Take consumes one request for key, applying policy atomically
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
+
+// Apply advances state to now and consumes one request when allowed
+func (p Policy) Apply(state State, now time.Time) (State, Result) {
+	if p.Algorithm == TokenBucket {
+		return p.applyTokenBucket(state, now)
+	}
+	return p.applySlidingWindow(state, now)
+}
+
+func (p Policy) applySlidingWindow(state State, now time.Time) (State, Result) {
+	limit := float64(p.Limit)
+	windowStart := now.Truncate(p.Window)
+
+	if !state.Start.Equal(windowStart) {
+		if state.Start.Equal(windowStart.Add(-p.Window)) {
+			state.Previous = state.Count
+		} else {
+			state.Previous = 0
+		}
+		state.Count = 0
+		state.Start = windowStart
+	}
+
+	elapsed := now.Sub(windowStart)
+	overlap := 1 - float64(elapsed)/float64(p.Window)
+	estimated := state.Previous*overlap + state.Count
+
+	result := Result{Limit: p.Limit, Reset: p.Window - elapsed}
+	if estimated+1 > limit {
+		result.RetryAfter = p.slidingRetryAfter(state, elapsed)
+		return state, result
+	}
+
+	state.Count++
+	result.Allowed = true
+	result.Remaining = int(math.Max(0, math.Floor(limit-estimated-1)))
+	return state, result
+}
+
+// slidingRetryAfter finds when the weighted previous count has decayed enough
+// for one more request, or the next window when this one alone is full
+func (p Policy) slidingRetryAfter(state State, elapsed time.Duration) time.Duration {
+	untilNextWindow := p.Window - elapsed
+	spare := float64(p.Limit) - state.Count - 1
+	if spare < 0 || state.Previous == 0 {
+		return untilNextWindow
+	}
+
+	// previous * (1 - t/window) <= spare
+	decayedAt := time.Duration((1 - spare/state.Previous) * float64(p.Window))
+	if wait := decayedAt - elapsed; wait > 0 && wait < untilNextWindow {
+		return wait
+	}
+	return untilNextWindow
+}
+
+func (p Policy) applyTokenBucket(state State, now time.Time) (State, Result) {
+	limit := float64(p.Limit)
+	perToken := p.Window / time.Duration(p.Limit)
+
+	if state.Start.IsZero() {
+		state.Count = limit
+	} else if elapsed := now.Sub(state.Start); elapsed > 0 {
+		state.Count = math.Min(limit, state.Count+float64(elapsed)/float64(perToken))
+	}
+	state.Start = now
+
+	result := Result{Limit: p.Limit}
+	if state.Count >= 1 {
+		state.Count--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - state.Count) * float64(perToken))
+	}
+
+	result.Remaining = int(math.Floor(state.Count))
+	result.Reset = time.Duration((limit - state.Count) * float64(perToken))
+	return state, result
+}
@@ -0,0 +1,149 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestApplySlidingWindow(t *testing.T) {
+	policy := Policy{Algorithm: SlidingWindow, Limit: 10, Window: time.Minute}
+
+	tests := []struct {
+		name  string
+		state State
+		now   time.Time
+		want  Result
+	}{
+		{
+			name: "fresh",
+			now:  base,
+			want: Result{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Minute},
+		},
+		{
+			name:  "last request in window",
+			state: State{Count: 9, Start: base},
+			now:   base.Add(10 * time.Second),
+			want:  Result{Allowed: true, Limit: 10, Remaining: 0, Reset: 50 * time.Second},
+		},
+		{
+			name:  "window full",
+			state: State{Count: 10, Start: base},
+			now:   base.Add(10 * time.Second),
+			want:  Result{Limit: 10, Reset: 50 * time.Second, RetryAfter: 50 * time.Second},
+		},
+		{
+			name:  "rollover weights the previous window",
+			state: State{Count: 10, Start: base},
+			now:   base.Add(90 * time.Second),
+			want:  Result{Allowed: true, Limit: 10, Remaining: 4, Reset: 30 * time.Second},
+		},
+		{
+			name:  "retry once the previous window decays",
+			state: State{Count: 5, Previous: 10, Start: base.Add(time.Minute)},
+			now:   base.Add(75 * time.Second),
+			want:  Result{Limit: 10, Reset: 45 * time.Second, RetryAfter: 21 * time.Second},
+		},
+		{
+			name:  "stale state is dropped",
+			state: State{Count: 10, Start: base.Add(-5 * time.Minute)},
+			now:   base,
+			want:  Result{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Minute},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, got := policy.Apply(test.state, test.now)
+			if got != test.want {
+				t.Errorf("Apply() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestApplyTokenBucket(t *testing.T) {
+	policy := Policy{Algorithm: TokenBucket, Limit: 10, Window: 10 * time.Second}
+
+	tests := []struct {
+		name  string
+		state State
+		now   time.Time
+		want  Result
+	}{
+		{
+			name: "fresh bucket is full",
+			now:  base,
+			want: Result{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:  "empty",
+			state: State{Count: 0, Start: base},
+			now:   base,
+			want:  Result{Limit: 10, Reset: 10 * time.Second, RetryAfter: time.Second},
+		},
+		{
+			name:  "partial token",
+			state: State{Count: 0.5, Start: base},
+			now:   base,
+			want:  Result{Limit: 10, Reset: 9500 * time.Millisecond, RetryAfter: 500 * time.Millisecond},
+		},
+		{
+			name:  "refills over time",
+			state: State{Count: 0, Start: base},
+			now:   base.Add(2500 * time.Millisecond),
+			want:  Result{Allowed: true, Limit: 10, Remaining: 1, Reset: 8500 * time.Millisecond},
+		},
+		{
+			name:  "refill is capped at the limit",
+			state: State{Count: 5, Start: base},
+			now:   base.Add(time.Hour),
+			want:  Result{Allowed: true, Limit: 10, Remaining: 9, Reset: time.Second},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, got := policy.Apply(test.state, test.now)
+			if got != test.want {
+				t.Errorf("Apply() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	policy := Policy{Algorithm: SlidingWindow, Limit: 2, Window: time.Minute}
+	ctx := context.Background()
+
+	take := func(key string, now time.Time) bool {
+		t.Helper()
+		result, err := store.Take(ctx, key, policy, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Allowed
+	}
+
+	if !take("a", base) || !take("a", base.Add(time.Second)) {
+		t.Fatal("requests within the limit were denied")
+	}
+	if take("a", base.Add(2*time.Second)) {
+		t.Fatal("request over the limit was allowed")
+	}
+	if !take("b", base.Add(2*time.Second)) {
+		t.Fatal("keys should not share a budget")
+	}
+
+	// Past Expiry the state is forgotten, and the sweep drops it
+	later := base.Add(policy.Expiry() + sweepInterval + time.Second)
+	if !take("a", later) {
+		t.Fatal("expired state was not reset")
+	}
+	if _, ok := store.entries["b"]; ok {
+		t.Fatal("expired entry was not swept")
+	}
+}
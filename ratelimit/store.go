@@ -0,0 +1,22 @@
+package ratelimit
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+)
+
+func NewStore(kind string, db *gorm.DB) (Store, error) {
+	switch kind {
+	case StoreMemory:
+		return NewMemoryStore(), nil
+	case StorePostgres:
+		return NewPostgresStore(db), nil
+	}
+	return nil, fmt.Errorf("unsupported rate limit store %q, expected %s or %s", kind, StoreMemory, StorePostgres)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/bparsons094/go-server-base/database"
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/bparsons094/go-server-base/requestlogs"
+	"github.com/bparsons094/go-server-base/utils"
+)
+
+var logger = logging.For("replayer")
+
+// Replays logged requests against another server and diffs the responses, e.g.
+//
+//	go run ./replayer -target http://localhost:4040 -token <jwt> -userId <uuid> -from 2024-01-01T00:00:00Z -speed 10
+func main() {
+	target := flag.String("target", "", "base URL to replay against (required)")
+	token := flag.String("token", "", "bearer token that replaces the logged Authorization header")
+	speed := flag.Float64("speed", 0, "1 keeps the original timing, N replays N times faster, 0 sends requests back to back")
+	ignore := flag.String("ignore", "createdAt,updatedAt", "comma separated JSON fields ignored when diffing responses")
+	timeout := flag.Duration("timeout", 30*time.Second, "per request timeout")
+	quiet := flag.Bool("quiet", false, "only print requests that differ or fail")
+
+	filterValues := map[string]*string{}
+	for _, key := range requestlogs.FilterKeys {
+		filterValues[key] = flag.String(key, "", "filter by "+key)
+	}
+	flag.Parse()
+
+	config := utils.LoadConfig("./")
+	logging.InitWithOutput(config, os.Stderr)
+
+	if *target == "" {
+		logging.Fatal(logger, "-target is required")
+	}
+	if *speed < 0 {
+		logging.Fatal(logger, "-speed must not be negative")
+	}
+
+	filter, err := requestlogs.ParseFilter(func(key string) string {
+		if value, ok := filterValues[key]; ok {
+			return *value
+		}
+		return ""
+	})
+	if err != nil {
+		logging.Fatal(logger, "Invalid filter", "error", err)
+	}
+
+	db := database.ConnectDB(config)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	options := requestlogs.ReplayOptions{
+		TargetURL:    *target,
+		Token:        *token,
+		Speed:        *speed,
+		IgnoreFields: utils.SplitList(*ignore),
+		Client:       &http.Client{Timeout: *timeout},
+	}
+
+	summary, err := requestlogs.Replay(ctx, db, filter, options, func(result requestlogs.ReplayResult) {
+		if *quiet && (result.Outcome == requestlogs.ReplayOK || result.Outcome == requestlogs.ReplaySkipped) {
+			return
+		}
+
+		fmt.Printf("%-5s #%d %s %s", result.Outcome, result.Entry.ID, result.Entry.Method, result.Entry.Path)
+		if result.StatusCode != 0 {
+			fmt.Printf(" (%d -> %d)", result.Entry.StatusCode, result.StatusCode)
+		}
+		if result.Reason != "" {
+			fmt.Printf(": %s", result.Reason)
+		}
+		fmt.Println()
+		if result.Diff != "" {
+			fmt.Println(result.Diff)
+		}
+	})
+	if err != nil {
+		logger.Error("Replay stopped", "error", err)
+	}
+
+	fmt.Printf("\n%d replayed: %d ok, %d differ, %d skipped, %d failed\n", summary.Total, summary.OK, summary.Diff, summary.Skipped, summary.Errors)
+
+	if err != nil || summary.Diff > 0 || summary.Errors > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,10 @@
+package requestlogs
+
+import "strings"
+
+// TruncationMarker is appended to captured bodies that were cut at the capture limit
+const TruncationMarker = "...[truncated]"
+
+func IsTruncated(body string) bool {
+	return strings.HasSuffix(body, TruncationMarker)
+}
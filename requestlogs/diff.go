@@ -0,0 +1,108 @@
+package requestlogs
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// NormalizeBody pretty prints JSON bodies with sorted keys and the ignored
+// fields removed so responses can be compared line by line. Non JSON bodies
+// are returned unchanged.
+func NormalizeBody(body string, ignoreFields []string) string {
+	var value interface{}
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		return body
+	}
+
+	value = dropFields(value, ignoreFields)
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return body
+	}
+	return buffer.String()
+}
+
+func dropFields(value interface{}, fields []string) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			if containsFold(fields, key) {
+				delete(typed, key)
+				continue
+			}
+			typed[key] = dropFields(nested, fields)
+		}
+	case []interface{}:
+		for i, nested := range typed {
+			typed[i] = dropFields(nested, fields)
+		}
+	}
+	return value
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// LineDiff returns a minimal line diff of expected and actual, prefixing
+// removed lines with "-", added lines with "+" and unchanged lines with " ".
+// It returns an empty string when both are equal.
+func LineDiff(expected string, actual string) string {
+	if expected == actual {
+		return ""
+	}
+
+	a := strings.Split(strings.TrimRight(expected, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(actual, "\n"), "\n")
+
+	// Longest common subsequence table
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff.WriteString("- " + a[i] + "\n")
+			i++
+		default:
+			diff.WriteString("+ " + b[j] + "\n")
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff.WriteString("- " + a[i] + "\n")
+	}
+	for ; j < len(b); j++ {
+		diff.WriteString("+ " + b[j] + "\n")
+	}
+	return diff.String()
+}
@@ -0,0 +1,39 @@
+package requestlogs
+
+import "testing"
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		actual   string
+		want     string
+	}{
+		{"equal", "a\nb", "a\nb", ""},
+		{"changed line", "a\nb\nc", "a\nx\nc", "  a\n- b\n+ x\n  c\n"},
+		{"added line", "a", "a\nb", "  a\n+ b\n"},
+		{"removed line", "a\nb\nc", "a\nc", "  a\n- b\n  c\n"},
+		{"shifted", "a\nb", "b\nc", "- a\n  b\n+ c\n"},
+		{"trailing newline only", "a\n", "a", "  a\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := LineDiff(test.expected, test.actual); got != test.want {
+				t.Errorf("LineDiff() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeBody(t *testing.T) {
+	body := `{"b":1,"a":{"ID":2,"x":"<y>"},"list":[{"id":3}]}`
+	want := "{\n  \"a\": {\n    \"x\": \"<y>\"\n  },\n  \"b\": 1,\n  \"list\": [\n    {}\n  ]\n}\n"
+
+	if got := NormalizeBody(body, []string{"id"}); got != want {
+		t.Errorf("NormalizeBody() = %q, want %q", got, want)
+	}
+	if got := NormalizeBody("not json", []string{"id"}); got != "not json" {
+		t.Errorf("NormalizeBody() = %q, want non JSON unchanged", got)
+	}
+}
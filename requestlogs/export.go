@@ -0,0 +1,191 @@
+package requestlogs
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/bparsons094/go-server-base/models"
+	"gorm.io/gorm"
+)
+
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+type ExportOptions struct {
+	Format    string
+	Gzip      bool
+	Redaction RedactionPolicy
+}
+
+func (o ExportOptions) Validate() error {
+	if o.Format != FormatNDJSON && o.Format != FormatCSV {
+		return fmt.Errorf("unsupported export format %q, expected %s or %s", o.Format, FormatNDJSON, FormatCSV)
+	}
+	return nil
+}
+
+func (o ExportOptions) ContentType() string {
+	if o.Gzip {
+		return "application/gzip"
+	}
+	if o.Format == FormatCSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+func (o ExportOptions) FileName(now time.Time) string {
+	name := fmt.Sprintf("request-logs-%s.%s", now.UTC().Format("20060102T150405Z"), o.Format)
+	if o.Gzip {
+		name += ".gz"
+	}
+	return name
+}
+
+// Record is the exported shape of a request log row
+type Record struct {
+	ID              int     `json:"id"`
+	RequestID       string  `json:"requestId"`
+	TraceID         string  `json:"traceId"`
+	AppVersion      string  `json:"appVersion"`
+	RequestTime     string  `json:"requestTime"`
+	ResponseTime    string  `json:"responseTime"`
+	UserID          string  `json:"userId"`
+	Duration        float64 `json:"duration"`
+	Method          string  `json:"method"`
+	Path            string  `json:"path"`
+	RouteTemplate   string  `json:"routeTemplate"`
+	StatusCode      int     `json:"statusCode"`
+	ClientIP        string  `json:"clientIp"`
+	UserAgent       string  `json:"userAgent"`
+	RequestSize     int     `json:"requestSize"`
+	ResponseSize    int     `json:"responseSize"`
+	Headers         string  `json:"headers"`
+	Body            string  `json:"body"`
+	Response        string  `json:"response"`
+	Error           string  `json:"error"`
+	QueryCount      int     `json:"queryCount"`
+	QueryDuration   float64 `json:"queryDuration"`
+	SlowQueries     int     `json:"slowQueries"`
+	RepeatedQueries string  `json:"repeatedQueries"`
+	SampleWeight    float64 `json:"sampleWeight"`
+}
+
+var csvHeader = []string{"id", "requestId", "traceId", "appVersion", "requestTime", "responseTime", "userId", "duration", "method", "path", "routeTemplate", "statusCode", "clientIp", "userAgent", "requestSize", "responseSize", "headers", "body", "response", "error", "queryCount", "queryDuration", "slowQueries", "repeatedQueries", "sampleWeight"}
+
+func NewRecord(entry models.RequestLog) Record {
+	record := Record{
+		ID:              entry.ID,
+		RequestID:       entry.RequestID,
+		TraceID:         entry.TraceID,
+		AppVersion:      entry.AppVersion,
+		RequestTime:     entry.RequestTime.UTC().Format(time.RFC3339Nano),
+		ResponseTime:    entry.ResponseTime.UTC().Format(time.RFC3339Nano),
+		Duration:        entry.Duration,
+		Method:          entry.Method,
+		Path:            entry.Path,
+		RouteTemplate:   entry.RouteTemplate,
+		StatusCode:      entry.StatusCode,
+		ClientIP:        entry.ClientIP,
+		UserAgent:       entry.UserAgent,
+		RequestSize:     entry.RequestSize,
+		ResponseSize:    entry.ResponseSize,
+		Headers:         entry.Headers,
+		Body:            entry.Body,
+		Response:        entry.Response,
+		Error:           entry.Error,
+		QueryCount:      entry.QueryCount,
+		QueryDuration:   entry.QueryDuration,
+		SlowQueries:     entry.SlowQueries,
+		RepeatedQueries: entry.RepeatedQueries,
+		SampleWeight:    entry.SampleWeight,
+	}
+	if entry.UserID != nil {
+		record.UserID = entry.UserID.String()
+	}
+	return record
+}
+
+func (r Record) csvRow() []string {
+	return []string{
+		strconv.Itoa(r.ID), r.RequestID, r.TraceID, r.AppVersion, r.RequestTime, r.ResponseTime, r.UserID,
+		strconv.FormatFloat(r.Duration, 'f', -1, 64), r.Method, r.Path, r.RouteTemplate,
+		strconv.Itoa(r.StatusCode), r.ClientIP, r.UserAgent,
+		strconv.Itoa(r.RequestSize), strconv.Itoa(r.ResponseSize),
+		r.Headers, r.Body, r.Response, r.Error,
+		strconv.Itoa(r.QueryCount), strconv.FormatFloat(r.QueryDuration, 'f', -1, 64),
+		strconv.Itoa(r.SlowQueries), r.RepeatedQueries,
+		strconv.FormatFloat(r.SampleWeight, 'f', -1, 64),
+	}
+}
+
+// Export streams the rows matching filter to w one at a time, so the full
+// result set is never held in memory. It returns the number of rows written.
+func Export(ctx context.Context, db *gorm.DB, filter Filter, options ExportOptions, w io.Writer) (count int, err error) {
+	if err := options.Validate(); err != nil {
+		return 0, err
+	}
+
+	if options.Gzip {
+		gzipWriter := gzip.NewWriter(w)
+		defer func() {
+			// Close writes the gzip footer, so its error matters
+			if closeErr := gzipWriter.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = gzipWriter
+	}
+
+	rows, err := db.WithContext(ctx).Model(&models.RequestLog{}).Scopes(filter.Scope).Order("id").Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var write func(Record) error
+	var flush func() error
+
+	switch options.Format {
+	case FormatCSV:
+		csvWriter := csv.NewWriter(w)
+		if err := csvWriter.Write(csvHeader); err != nil {
+			return 0, err
+		}
+		write = func(record Record) error { return csvWriter.Write(record.csvRow()) }
+		flush = func() error {
+			csvWriter.Flush()
+			return csvWriter.Error()
+		}
+	default:
+		encoder := json.NewEncoder(w)
+		write = func(record Record) error { return encoder.Encode(record) }
+		flush = func() error { return nil }
+	}
+
+	for rows.Next() {
+		var entry models.RequestLog
+		if err := db.ScanRows(rows, &entry); err != nil {
+			return count, err
+		}
+
+		options.Redaction.Apply(&entry)
+		if err := write(NewRecord(entry)); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	return count, flush()
+}
@@ -0,0 +1,131 @@
+package requestlogs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Filter selects request log rows. Zero values are ignored.
+type Filter struct {
+	UserID        *uuid.UUID
+	From          *time.Time
+	To            *time.Time
+	Method        string
+	PathPrefix    string
+	RouteTemplate string
+	RequestID     string
+	AppVersion    string
+	MinStatus     int
+	MaxStatus     int
+	IDs           []int
+	Limit         int
+}
+
+// FilterKeys are the parameter names understood by ParseFilter
+var FilterKeys = []string{"userId", "from", "to", "method", "path", "route", "requestId", "appVersion", "minStatus", "maxStatus", "ids", "limit"}
+
+// ParseFilter builds a Filter from named string parameters, such as query
+// parameters or CLI flags. Times are RFC3339.
+func ParseFilter(get func(key string) string) (Filter, error) {
+	var filter Filter
+
+	if value := get("userId"); value != "" {
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid userId: %w", err)
+		}
+		filter.UserID = &userID
+	}
+
+	for key, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := get(key); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s, expected RFC3339: %w", key, err)
+			}
+			*target = &parsed
+		}
+	}
+
+	for key, target := range map[string]*int{"minStatus": &filter.MinStatus, "maxStatus": &filter.MaxStatus, "limit": &filter.Limit} {
+		if value := get(key); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				return filter, fmt.Errorf("invalid %s: %q", key, value)
+			}
+			*target = parsed
+		}
+	}
+
+	if value := get("ids"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return filter, fmt.Errorf("invalid ids: %q", part)
+			}
+			filter.IDs = append(filter.IDs, id)
+		}
+	}
+
+	filter.Method = strings.ToUpper(get("method"))
+	filter.PathPrefix = get("path")
+	filter.RouteTemplate = get("route")
+	filter.RequestID = get("requestId")
+	filter.AppVersion = get("appVersion")
+
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return filter, fmt.Errorf("to must not be before from")
+	}
+
+	return filter, nil
+}
+
+// Scope applies the filter to a query on request_logs
+func (f Filter) Scope(db *gorm.DB) *gorm.DB {
+	if f.UserID != nil {
+		db = db.Where("user_id = ?", *f.UserID)
+	}
+	if f.From != nil {
+		db = db.Where("request_time >= ?", *f.From)
+	}
+	if f.To != nil {
+		db = db.Where("request_time < ?", *f.To)
+	}
+	if f.Method != "" {
+		db = db.Where("method = ?", f.Method)
+	}
+	if f.PathPrefix != "" {
+		db = db.Where("path LIKE ?", escapeLike(f.PathPrefix)+"%")
+	}
+	if f.RouteTemplate != "" {
+		db = db.Where("route_template = ?", f.RouteTemplate)
+	}
+	if f.RequestID != "" {
+		db = db.Where("request_id = ?", f.RequestID)
+	}
+	if f.AppVersion != "" {
+		db = db.Where("app_version = ?", f.AppVersion)
+	}
+	if f.MinStatus > 0 {
+		db = db.Where("status_code >= ?", f.MinStatus)
+	}
+	if f.MaxStatus > 0 {
+		db = db.Where("status_code <= ?", f.MaxStatus)
+	}
+	if len(f.IDs) > 0 {
+		db = db.Where("id IN ?", f.IDs)
+	}
+	if f.Limit > 0 {
+		db = db.Limit(f.Limit)
+	}
+	return db
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,144 @@
+package requestlogs
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/bparsons094/go-server-base/models"
+)
+
+const RedactedValue = "[REDACTED]"
+
+// RedactionPolicy lists the headers and body fields whose values must never
+// leave the server. Matching is case-insensitive.
+type RedactionPolicy struct {
+	Headers    []string
+	BodyFields []string
+}
+
+func DefaultRedactionPolicy() RedactionPolicy {
+	return RedactionPolicy{
+		Headers:    []string{"Authorization", "Cookie", "Set-Cookie", "X-API-Key", "X-Debug-Token", "Proxy-Authorization"},
+		BodyFields: []string{"password", "currentPassword", "newPassword", "token", "accessToken", "refreshToken", "secret", "apiKey", "clientSecret"},
+	}
+}
+
+// Apply redacts the headers, request body and response body of the entry in place
+func (p RedactionPolicy) Apply(entry *models.RequestLog) {
+	entry.Headers = p.RedactHeaders(entry.Headers)
+	entry.Body = p.RedactBody(entry.Body)
+	entry.Response = p.RedactBody(entry.Response)
+}
+
+// RedactHeaders works on the raw "Name: value" header block stored on request logs
+func (p RedactionPolicy) RedactHeaders(headers string) string {
+	lines := strings.Split(headers, "\n")
+	for i, line := range lines {
+		name, _, found := strings.Cut(line, ":")
+		if found && p.isRedactedHeader(strings.TrimSpace(name)) {
+			lines[i] = name + ": " + RedactedValue + trailingCarriageReturn(line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RedactBody redacts JSON and form encoded bodies. Anything else is returned as is.
+func (p RedactionPolicy) RedactBody(body string) string {
+	trimmed := strings.TrimSpace(body)
+	if trimmed == "" {
+		return body
+	}
+
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var value interface{}
+		if err := json.Unmarshal([]byte(trimmed), &value); err != nil {
+			// Truncated or malformed JSON still gets its sensitive string fields masked
+			return p.jsonFieldPattern().ReplaceAllString(body, `${1}"`+RedactedValue+`"`)
+		}
+
+		redacted, err := json.Marshal(p.redactValue(value))
+		if err != nil {
+			return body
+		}
+		return string(redacted)
+	}
+
+	if values, err := url.ParseQuery(trimmed); err == nil && strings.Contains(trimmed, "=") {
+		changed := false
+		for key := range values {
+			if p.isRedactedField(key) {
+				values.Set(key, RedactedValue)
+				changed = true
+			}
+		}
+		if changed {
+			return values.Encode()
+		}
+	}
+
+	return body
+}
+
+func (p RedactionPolicy) redactValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			if p.isRedactedField(key) {
+				typed[key] = RedactedValue
+			} else {
+				typed[key] = p.redactValue(nested)
+			}
+		}
+	case []interface{}:
+		for i, nested := range typed {
+			typed[i] = p.redactValue(nested)
+		}
+	}
+	return value
+}
+
+var jsonFieldPatterns sync.Map
+
+// Matches "field": "value" pairs for the policy's fields, including a value cut off by truncation
+func (p RedactionPolicy) jsonFieldPattern() *regexp.Regexp {
+	key := strings.Join(p.BodyFields, ",")
+	if pattern, ok := jsonFieldPatterns.Load(key); ok {
+		return pattern.(*regexp.Regexp)
+	}
+
+	fields := make([]string, len(p.BodyFields))
+	for i, field := range p.BodyFields {
+		fields[i] = regexp.QuoteMeta(field)
+	}
+	pattern := regexp.MustCompile(`(?i)("(?:` + strings.Join(fields, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+	jsonFieldPatterns.Store(key, pattern)
+	return pattern
+}
+
+func (p RedactionPolicy) isRedactedHeader(name string) bool {
+	for _, header := range p.Headers {
+		if strings.EqualFold(header, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RedactionPolicy) isRedactedField(name string) bool {
+	for _, field := range p.BodyFields {
+		if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func trailingCarriageReturn(line string) string {
+	if strings.HasSuffix(line, "\r") {
+		return "\r"
+	}
+	return ""
+}
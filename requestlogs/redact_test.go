@@ -0,0 +1,65 @@
+package requestlogs
+
+import "testing"
+
+func TestRedactHeaders(t *testing.T) {
+	headers := "Authorization: Bearer abc\r\nAccept: */*\r\ncookie: session=1\r\nX-Debug-Token:secret"
+	want := "Authorization: [REDACTED]\r\nAccept: */*\r\ncookie: [REDACTED]\r\nX-Debug-Token: [REDACTED]"
+
+	if got := DefaultRedactionPolicy().RedactHeaders(headers); got != want {
+		t.Errorf("RedactHeaders() = %q, want %q", got, want)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "nested json",
+			body: `{"user":{"Password":"hunter2","name":"ada"},"items":[{"token":"t"}]}`,
+			want: `{"items":[{"token":"[REDACTED]"}],"user":{"Password":"[REDACTED]","name":"ada"}}`,
+		},
+		{
+			name: "truncated json",
+			body: `{"name":"ada","password":"hunt`,
+			want: `{"name":"ada","password":"[REDACTED]"`,
+		},
+		{
+			name: "truncated json with escapes",
+			body: `{"secret": "a\"b", "name":"ada"`,
+			want: `{"secret": "[REDACTED]", "name":"ada"`,
+		},
+		{
+			name: "form",
+			body: "password=hunter2&user=ada",
+			want: "password=%5BREDACTED%5D&user=ada",
+		},
+		{
+			name: "form without sensitive fields",
+			body: "user=ada&b=c",
+			want: "user=ada&b=c",
+		},
+		{
+			name: "plain text",
+			body: "password is hunter2",
+			want: "password is hunter2",
+		},
+		{
+			name: "empty",
+			body: "",
+			want: "",
+		},
+	}
+
+	policy := DefaultRedactionPolicy()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := policy.RedactBody(test.body); got != test.want {
+				t.Errorf("RedactBody() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package requestlogs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/bparsons094/go-server-base/models"
+	"gorm.io/gorm"
+)
+
+const (
+	ReplayOK      = "OK"
+	ReplayDiff    = "DIFF"
+	ReplaySkipped = "SKIP"
+	ReplayError   = "ERROR"
+)
+
+// Headers that describe the original connection rather than the request
+var skippedReplayHeaders = []string{"Host", "Content-Length", "Connection", "Keep-Alive", "Transfer-Encoding", "Upgrade", "Accept-Encoding", "X-Request-Id"}
+
+type ReplayOptions struct {
+	// Base URL the requests are sent to, e.g. http://localhost:4040
+	TargetURL string
+	// Replaces the stored Authorization header when set
+	Token string
+	// 1 keeps the original gaps between requests, 10 replays ten times
+	// faster and 0 sends them back to back
+	Speed float64
+	// JSON fields left out of the response comparison, e.g. timestamps
+	IgnoreFields []string
+	Client       *http.Client
+}
+
+type ReplayResult struct {
+	Entry      models.RequestLog
+	Outcome    string
+	StatusCode int
+	Diff       string
+	Reason     string
+}
+
+type ReplaySummary struct {
+	Total   int
+	OK      int
+	Diff    int
+	Skipped int
+	Errors  int
+}
+
+// Replay sends the logged requests matching filter to the target in their
+// original order, calling report with the outcome of each one.
+func Replay(ctx context.Context, db *gorm.DB, filter Filter, options ReplayOptions, report func(ReplayResult)) (ReplaySummary, error) {
+	var summary ReplaySummary
+
+	if options.Client == nil {
+		options.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	options.TargetURL = strings.TrimRight(options.TargetURL, "/")
+
+	rows, err := db.WithContext(ctx).Model(&models.RequestLog{}).Scopes(filter.Scope).Order("request_time, id").Rows()
+	if err != nil {
+		return summary, err
+	}
+	defer rows.Close()
+
+	var previous time.Time
+	for rows.Next() {
+		var entry models.RequestLog
+		if err := db.ScanRows(rows, &entry); err != nil {
+			return summary, err
+		}
+
+		if options.Speed > 0 && !previous.IsZero() {
+			wait := time.Duration(float64(entry.RequestTime.Sub(previous)) / options.Speed)
+			select {
+			case <-ctx.Done():
+				return summary, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+		previous = entry.RequestTime
+
+		result := replayEntry(ctx, entry, options)
+		summary.Total++
+		switch result.Outcome {
+		case ReplayOK:
+			summary.OK++
+		case ReplayDiff:
+			summary.Diff++
+		case ReplaySkipped:
+			summary.Skipped++
+		default:
+			summary.Errors++
+		}
+		report(result)
+	}
+
+	return summary, rows.Err()
+}
+
+func replayEntry(ctx context.Context, entry models.RequestLog, options ReplayOptions) ReplayResult {
+	result := ReplayResult{Entry: entry}
+
+	if IsTruncated(entry.Body) || (entry.Body == "" && entry.RequestSize > 0) {
+		result.Outcome = ReplaySkipped
+		result.Reason = "request body was not fully captured"
+		return result
+	}
+
+	requestURI, headers := ParseRawHeaders(entry.Headers)
+	if requestURI == "" {
+		requestURI = entry.Path
+	}
+
+	request, err := http.NewRequestWithContext(ctx, entry.Method, options.TargetURL+requestURI, strings.NewReader(entry.Body))
+	if err != nil {
+		result.Outcome = ReplayError
+		result.Reason = err.Error()
+		return result
+	}
+
+	for name, values := range headers {
+		if containsFold(skippedReplayHeaders, name) {
+			continue
+		}
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+	if options.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+options.Token)
+	}
+	if entry.RequestID != "" {
+		request.Header.Set("X-Replay-Of", entry.RequestID)
+	}
+
+	response, err := options.Client.Do(request)
+	if err != nil {
+		result.Outcome = ReplayError
+		result.Reason = err.Error()
+		return result
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		result.Outcome = ReplayError
+		result.Reason = err.Error()
+		return result
+	}
+	result.StatusCode = response.StatusCode
+
+	var diff strings.Builder
+	if entry.StatusCode != 0 && entry.StatusCode != response.StatusCode {
+		fmt.Fprintf(&diff, "- status %d\n+ status %d\n", entry.StatusCode, response.StatusCode)
+	}
+
+	// Responses that were truncated or not captured can only be compared by status
+	if entry.Response != "" && !IsTruncated(entry.Response) {
+		diff.WriteString(LineDiff(
+			NormalizeBody(entry.Response, options.IgnoreFields),
+			NormalizeBody(string(body), options.IgnoreFields),
+		))
+	}
+
+	result.Diff = diff.String()
+	if result.Diff == "" {
+		result.Outcome = ReplayOK
+	} else {
+		result.Outcome = ReplayDiff
+	}
+	return result
+}
+
+// ParseRawHeaders splits the raw header block stored on request logs into
+// the request URI from the request line and the header fields.
+func ParseRawHeaders(raw string) (string, http.Header) {
+	reader := textproto.NewReader(bufio.NewReader(strings.NewReader(raw)))
+
+	requestLine, err := reader.ReadLine()
+	if err != nil {
+		return "", http.Header{}
+	}
+
+	var requestURI string
+	if parts := strings.Fields(requestLine); len(parts) == 3 {
+		requestURI = parts[1]
+	}
+
+	mimeHeader, err := reader.ReadMIMEHeader()
+	if err != nil && len(mimeHeader) == 0 {
+		return requestURI, http.Header{}
+	}
+	return requestURI, http.Header(mimeHeader)
+}
@@ -0,0 +1,166 @@
+package requestlogs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bparsons094/go-server-base/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Request logs are written asynchronously, so buckets stay open for a little
+// while after they end before the watermark moves past them
+const rollupGracePeriod = 5 * time.Minute
+
+type Granularity struct {
+	Name  string
+	unit  string
+	size  time.Duration
+	table string
+	// Caps how much history a single run processes when catching up
+	maxRange time.Duration
+}
+
+var (
+	Hourly = Granularity{Name: "hourly", unit: "hour", size: time.Hour, table: "request_log_hourly_rollups", maxRange: 7 * 24 * time.Hour}
+	Daily  = Granularity{Name: "daily", unit: "day", size: 24 * time.Hour, table: "request_log_daily_rollups", maxRange: 90 * 24 * time.Hour}
+)
+
+func GranularityByName(name string) (Granularity, error) {
+	switch name {
+	case Hourly.Name:
+		return Hourly, nil
+	case Daily.Name:
+		return Daily, nil
+	}
+	return Granularity{}, fmt.Errorf("unknown granularity %q, expected %s or %s", name, Hourly.Name, Daily.Name)
+}
+
+func (g Granularity) Truncate(t time.Time) time.Time {
+	return t.UTC().Truncate(g.size)
+}
+
+// ComputeRollups brings the granularity's rollup table up to date. It starts
+// at the stored watermark, recomputes every bucket up to and including the
+// current one and returns the range it processed.
+func ComputeRollups(ctx context.Context, db *gorm.DB, g Granularity, now time.Time) (from time.Time, to time.Time, err error) {
+	db = db.WithContext(ctx)
+
+	var watermark models.RollupWatermark
+	err = db.Where("name = ?", g.Name).Take(&watermark).Error
+	switch {
+	case err == nil:
+		from = watermark.ProcessedUntil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		var earliest *time.Time
+		if err := db.Model(&models.RequestLog{}).Select("min(request_time)").Scan(&earliest).Error; err != nil {
+			return from, to, err
+		}
+		if earliest == nil {
+			return from, to, nil
+		}
+		from = g.Truncate(*earliest)
+	default:
+		return from, to, err
+	}
+
+	to = g.Truncate(now).Add(g.size)
+	if to.Sub(from) > g.maxRange {
+		to = from.Add(g.maxRange)
+	}
+
+	processedUntil := g.Truncate(now.Add(-rollupGracePeriod))
+	if processedUntil.After(to) {
+		processedUntil = to
+	}
+	if processedUntil.Before(from) {
+		processedUntil = from
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, dimension := range []struct{ name, value, condition string }{
+			{models.RollupDimensionRoute, "method || ' ' || route_template", "route_template <> ''"},
+			{models.RollupDimensionUser, "user_id::text", "user_id IS NOT NULL"},
+		} {
+			if err := tx.Exec(rollupSQL(g, dimension.value, dimension.condition), from, to, dimension.name).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&models.RollupWatermark{
+			Name:           g.Name,
+			ProcessedUntil: processedUntil,
+		}).Error
+	})
+
+	return from, to, err
+}
+
+// Only built from the fixed granularities and dimensions above, never from input.
+//
+// Rows are weighted by sample_weight, since failed requests are always logged
+// while the rest are sampled. Percentiles are the smallest duration whose
+// cumulative weight reaches the quantile.
+func rollupSQL(g Granularity, valueExpression string, condition string) string {
+	bucket := fmt.Sprintf("date_trunc('%s', request_time AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'", g.unit)
+
+	return fmt.Sprintf(`
+		WITH logs AS (
+			SELECT %[2]s AS bucket_start, %[3]s AS dimension_value, status_code, duration, sample_weight,
+				sum(sample_weight) OVER (PARTITION BY %[2]s, %[3]s ORDER BY duration ROWS UNBOUNDED PRECEDING) AS cumulative_weight,
+				sum(sample_weight) OVER (PARTITION BY %[2]s, %[3]s) AS total_weight
+			FROM request_logs
+			WHERE request_time >= ? AND request_time < ? AND %[4]s
+		)
+		INSERT INTO %[1]s (bucket_start, dimension, dimension_value, request_count, error_count, client_error_count, p50_ms, p95_ms, p99_ms, updated_at)
+		SELECT bucket_start, ?, dimension_value,
+			round(sum(sample_weight)),
+			coalesce(round(sum(sample_weight) FILTER (WHERE status_code >= 500)), 0),
+			coalesce(round(sum(sample_weight) FILTER (WHERE status_code >= 400 AND status_code < 500)), 0),
+			min(duration) FILTER (WHERE cumulative_weight >= 0.50 * total_weight) * 1000,
+			min(duration) FILTER (WHERE cumulative_weight >= 0.95 * total_weight) * 1000,
+			min(duration) FILTER (WHERE cumulative_weight >= 0.99 * total_weight) * 1000,
+			now()
+		FROM logs
+		GROUP BY bucket_start, dimension_value
+		ON CONFLICT (bucket_start, dimension, dimension_value) DO UPDATE SET
+			request_count = EXCLUDED.request_count,
+			error_count = EXCLUDED.error_count,
+			client_error_count = EXCLUDED.client_error_count,
+			p50_ms = EXCLUDED.p50_ms,
+			p95_ms = EXCLUDED.p95_ms,
+			p99_ms = EXCLUDED.p99_ms,
+			updated_at = EXCLUDED.updated_at`,
+		g.table, bucket, valueExpression, condition)
+}
+
+type RollupQuery struct {
+	Dimension      string
+	DimensionValue string
+	From           *time.Time
+	To             *time.Time
+	Limit          int
+}
+
+func QueryRollups(ctx context.Context, db *gorm.DB, g Granularity, query RollupQuery) ([]models.RequestLogRollup, error) {
+	statement := db.WithContext(ctx).Table(g.table).Where("dimension = ?", query.Dimension)
+	if query.DimensionValue != "" {
+		statement = statement.Where("dimension_value = ?", query.DimensionValue)
+	}
+	if query.From != nil {
+		statement = statement.Where("bucket_start >= ?", *query.From)
+	}
+	if query.To != nil {
+		statement = statement.Where("bucket_start < ?", *query.To)
+	}
+	if query.Limit > 0 {
+		statement = statement.Limit(query.Limit)
+	}
+
+	var rollups []models.RequestLogRollup
+	err := statement.Order("bucket_start, dimension_value").Find(&rollups).Error
+	return rollups, err
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"github.com/bparsons094/go-server-base/controllers"
+	"github.com/bparsons094/go-server-base/middleware"
+	"github.com/bparsons094/go-server-base/openapi"
+	"github.com/bparsons094/go-server-base/requestlogs"
+	"github.com/gofiber/fiber/v2"
+)
+
+func AdminRoutes(api fiber.Router) {
+	adminRoutes := api.Group("/admin", middleware.RequireAdmin)
+	openapi.Route(adminRoutes, fiber.MethodGet, "/requestLogs/export", openapi.Operation{
+		Summary:     "Export request logs",
+		Description: "Streams matching request logs as NDJSON or CSV, gzipped when gzip is set. Headers and bodies are redacted.",
+		Tags:        []string{"admin"},
+		Query:       controllers.RequestLogExportQueryParams{},
+		Response:    requestlogs.Record{},
+		ContentType: "application/x-ndjson",
+	}, controllers.ExportRequestLogs)
+	openapi.Route(adminRoutes, fiber.MethodGet, "/analytics/routes", openapi.Operation{
+		Summary:  "Request rollups by route",
+		Tags:     []string{"admin"},
+		Query:    controllers.RouteRollupQueryParams{},
+		Response: controllers.RollupsResponse{},
+	}, controllers.GetRouteAnalytics)
+	openapi.Route(adminRoutes, fiber.MethodGet, "/analytics/users", openapi.Operation{
+		Summary:  "Request rollups by user",
+		Tags:     []string{"admin"},
+		Query:    controllers.UserRollupQueryParams{},
+		Response: controllers.RollupsResponse{},
+	}, controllers.GetUserAnalytics)
+}
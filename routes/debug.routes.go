@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/bparsons094/go-server-base/controllers"
+	"github.com/bparsons094/go-server-base/middleware"
+	"github.com/bparsons094/go-server-base/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
+)
+
+// DebugRoutes exposes pprof and runtime introspection under /internal/debug.
+// They are only registered when DEBUG_ENDPOINTS_ENABLED is on, which is the
+// default in local only.
+func DebugRoutes(app *fiber.App, config utils.Config) {
+	if !config.DebugEndpointsEnabled {
+		return
+	}
+
+	debugRoutes := app.Group("/internal/debug", middleware.DebugAccess(config.DebugToken), middleware.RequireAdminOrDebugToken)
+
+	// Serves /internal/debug/pprof/*
+	debugRoutes.Use(pprof.New(pprof.Config{Prefix: "/internal"}))
+
+	debugRoutes.Get("/goroutines", controllers.GetGoroutineDump)
+	debugRoutes.Get("/gc", controllers.GetGCStats)
+	debugRoutes.Get("/logLevels", controllers.GetLogLevels)
+	debugRoutes.Put("/logLevels", controllers.SetLogLevel)
+	debugRoutes.Delete("/logLevels/:component", controllers.ResetLogLevel)
+}
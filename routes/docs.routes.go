@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+	"net/http"
+
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/bparsons094/go-server-base/middleware"
+	"github.com/bparsons094/go-server-base/openapi"
+	"github.com/bparsons094/go-server-base/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/filesystem"
+)
+
+// Swagger UI is served from the binary rather than a CDN, so the docs page
+// runs no third party code. The files are swagger-ui 5.18.2, copied from the
+// dist of github.com/swaggo/files/v2@v2.0.2 so the Go checksum database
+// verifies them. Embedding them by name fails the build if either is missing.
+//
+//go:generate sh -c "go mod download -json github.com/swaggo/files/v2@v2.0.2 | sed -n 's|.*\"Dir\": \"\\(.*\\)\",|\\1/dist/swagger-ui-bundle.js \\1/dist/swagger-ui.css swaggerui/|p' | xargs install -m 644"
+//go:embed swaggerui/swagger-ui-bundle.js swaggerui/swagger-ui.css
+var swaggerUIFiles embed.FS
+
+var swaggerUIPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<title>API Docs</title>
+	<link rel="stylesheet" href="/api/docs/assets/swagger-ui.css">
+</head>
+<body>
+	<div id="swagger-ui"></div>
+	<script nonce="{{.Nonce}}" src="/api/docs/assets/swagger-ui-bundle.js"></script>
+	<script nonce="{{.Nonce}}">
+		window.ui = SwaggerUIBundle({url: "{{.SpecURL}}", dom_id: "#swagger-ui"});
+	</script>
+</body>
+</html>
+`))
+
+// DocsRoutes serves the OpenAPI document and a Swagger UI page for it when
+// API_DOCS_ENABLED. They are registered ahead of the /api group so they skip
+// authentication and version negotiation.
+func DocsRoutes(app *fiber.App, config utils.Config) {
+	if !config.APIDocsEnabled {
+		return
+	}
+
+	app.Get("/api/openapi.json", openapi.Handler)
+
+	assets, err := fs.Sub(swaggerUIFiles, "swaggerui")
+	if err != nil {
+		logging.Fatal(logger, "Error loading the Swagger UI assets", "error", err)
+	}
+
+	app.Use("/api/docs/assets", filesystem.New(filesystem.Config{
+		Root:   http.FS(assets),
+		MaxAge: 86400,
+	}))
+	app.Get("/api/docs", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return swaggerUIPage.Execute(c, fiber.Map{
+			"Nonce":   middleware.GetCSPNonce(c),
+			"SpecURL": "/api/openapi.json",
+		})
+	})
+}
@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/bparsons094/go-server-base/buildinfo"
+	"github.com/bparsons094/go-server-base/health"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/monitor"
+)
+
+var (
+	startTime = time.Now().UTC()
+)
+
+func HealthRoutes(app *fiber.App) {
+	app.Get("/version", getVersion)
+	app.Get("/health", getHealth)
+	app.Get("/health/live", getLiveness)
+	app.Get("/health/ready", getReadiness)
+	app.Get("/health/monitor", monitor.New(monitor.Config{
+		Title: "Server Health Monitor",
+	}))
+}
+
+// Liveness only proves the process can serve requests. Dependencies are left
+// to readiness so a database outage doesn't get the pod restarted.
+func getLiveness(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"status": health.StatusUp})
+}
+
+func getReadiness(c *fiber.Ctx) error {
+	report := health.Ready(c.UserContext())
+	return c.Status(reportStatusCode(report)).JSON(report)
+}
+
+func getHealth(c *fiber.Ctx) error {
+
+	type Health struct {
+		Status        string          `json:"status"`
+		Uptime        string          `json:"uptime"`
+		AppVersion    string          `json:"app_version"`
+		Build         buildinfo.Info  `json:"build"`
+		MemoryUsage   uint64          `json:"memory_usage"`
+		NumGoroutine  int             `json:"num_goroutine"`
+		NumCPU        int             `json:"num_cpu"`
+		DatabaseAlive bool            `json:"database_alive"`
+		Checks        []health.Result `json:"checks"`
+	}
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	report := health.Detailed(c.UserContext())
+	build := buildinfo.Get()
+
+	dbAlive := false
+	for _, check := range report.Checks {
+		if check.Name == "database" {
+			dbAlive = check.Status == health.StatusUp
+		}
+	}
+
+	healthReport := Health{
+		Status:        report.Status,
+		Uptime:        time.Since(startTime).String(),
+		AppVersion:    build.Version,
+		Build:         build,
+		MemoryUsage:   memStats.Alloc,
+		NumGoroutine:  runtime.NumGoroutine(),
+		NumCPU:        runtime.NumCPU(),
+		DatabaseAlive: dbAlive,
+		Checks:        report.Checks,
+	}
+
+	return c.Status(reportStatusCode(report)).JSON(healthReport)
+}
+
+func getVersion(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(buildinfo.Get())
+}
+
+func reportStatusCode(report health.Report) int {
+	if report.Status != health.StatusUp {
+		return fiber.StatusServiceUnavailable
+	}
+	return fiber.StatusOK
+}
@@ -1,28 +1,41 @@
 package routes
 
 import (
-	"log"
-	"runtime"
-	"time"
-
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/bparsons094/go-server-base/buildinfo"
 	"github.com/bparsons094/go-server-base/database"
+	"github.com/bparsons094/go-server-base/health"
+	"github.com/bparsons094/go-server-base/lifecycle"
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/bparsons094/go-server-base/metrics"
 	"github.com/bparsons094/go-server-base/middleware"
+	"github.com/bparsons094/go-server-base/openapi"
+	"github.com/bparsons094/go-server-base/ratelimit"
 	"github.com/bparsons094/go-server-base/utils"
 	"github.com/bparsons094/go-server-base/websockets"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/compress"
-	"github.com/gofiber/fiber/v2/middleware/monitor"
 	"github.com/gofiber/websocket/v2"
 )
 
-var (
-	startTime = time.Now().UTC()
-)
+var logger = logging.For("routes")
 
 func SetupRoutes(app *fiber.App, config utils.Config) {
 	DB := database.GetDatabase()
 
+	rateLimitStore, err := ratelimit.NewStore(config.RateLimitStore, DB)
+	if err != nil {
+		logging.Fatal(logger, "Error creating the rate limit store", "error", err)
+	}
+
+	requestLogs := middleware.LogMiddleware(DB, middleware.RequestLogConfig{
+		Default: middleware.DefaultRequestLogPolicy(config),
+		Routes:  requestLogRoutes(config),
+	})
+
 	HealthRoutes(app)
+	SecurityRoutes(app, rateLimitStore)
+	app.Get("/metrics", middleware.MetricsAccess(config.MetricsToken, config.DebugToken), middleware.RequireAdminOrDebugToken, metrics.Handler())
 
 	// Websocket routes
 	service := websockets.NewWebSocketService()
@@ -30,59 +43,77 @@ func SetupRoutes(app *fiber.App, config utils.Config) {
 		c.Locals("service", service)
 		return c.Next()
 	})
-	app.Use("/ws", websocket.New(func(c *websocket.Conn) {
+	apiKeys := middleware.APIKey(func() []string {
+		return utils.SplitList(utils.GetConfig().APIKeys)
+	})
+	app.Use("/ws", requestLogs, apiKeys, middleware.RateLimit(rateLimitStore, "ws", func() ratelimit.Policy {
+		current := utils.GetConfig()
+		return ratelimit.Policy{Algorithm: current.RateLimitAlgorithm, Limit: current.RateLimitWebSocketRequests, Window: current.RateLimitWebSocketWindow}
+	}), websocket.New(func(c *websocket.Conn) {
 		service.HandleWebSocketConnection(c)
 	}))
+	health.Register("websocket", service.HealthCheck, health.Options{})
+	lifecycle.Register(lifecycle.Hook{Name: "websocket", Order: lifecycle.OrderWebSocket, Stop: service.Shutdown})
 
 	// Internal routes
+	DocsRoutes(app, config)
+	app.Use("/api", middleware.APIVersion("/api", func() string {
+		return utils.GetConfig().APIDefaultVersion
+	}))
 	api := app.Group("/api")
+	api.Use(apiKeys)
+	api.Use(middleware.RateLimitByIP(rateLimitStore, "api_ip", func() ratelimit.Policy {
+		current := utils.GetConfig()
+		return ratelimit.Policy{Algorithm: current.RateLimitAlgorithm, Limit: current.RateLimitIPRequests, Window: current.RateLimitIPWindow}
+	}))
 	api.Use(middleware.AuthenticateUser)
 	api.Use(compress.New(compress.Config{
 		Level: compress.LevelDefault,
 	}))
-	api.Use(middleware.LogMiddleware(DB))
-
-	app.Use(func(c *fiber.Ctx) error {
-		return c.Status(404).JSON(fiber.Map{"status": "error", "message": "Route Not found"})
-	})
-
-}
-
-func HealthRoutes(app *fiber.App) {
-	app.Get("/health", getHealth)
-	app.Get("/health/monitor", monitor.New(monitor.Config{
-		Title: "Server Health Monitor",
+	api.Use(requestLogs)
+	health.Register("request_log_writer", middleware.RequestLogWriterHealth, health.Options{})
+	api.Use(middleware.RateLimit(rateLimitStore, "api", func() ratelimit.Policy {
+		current := utils.GetConfig()
+		return ratelimit.Policy{Algorithm: current.RateLimitAlgorithm, Limit: current.RateLimitAPIRequests, Window: current.RateLimitAPIWindow}
 	}))
-}
+	api.Use(middleware.Idempotency(DB))
+	lifecycle.Register(lifecycle.Hook{Name: "request_log_writer", Order: lifecycle.OrderRequestLogWriter, Stop: middleware.DrainRequestLogWriter})
+
+	// v2 serves the same routes as v1 until one of them changes shape. Mark the
+	// v1 route with middleware.Deprecated when it does.
+	for _, version := range middleware.APIVersions {
+		versioned := api.Group("/" + version)
+		UserRoutes(versioned)
+		AdminRoutes(versioned)
+	}
 
-func getHealth(c *fiber.Ctx) error {
+	DebugRoutes(app, config)
 
-	type Health struct {
-		Uptime        string `json:"uptime"`
-		AppVersion    string `json:"app_version"`
-		MemoryUsage   uint64 `json:"memory_usage"`
-		NumGoroutine  int    `json:"num_goroutine"`
-		NumCPU        int    `json:"num_cpu"`
-		DatabaseAlive bool   `json:"database_alive"`
+	if err := openapi.Build(openapi.Info{Title: config.TracingServiceName, Version: buildinfo.Get().Version}); err != nil {
+		logging.Fatal(logger, "Error building the OpenAPI document", "error", err)
 	}
 
-	var memStats runtime.MemStats
-	runtime.ReadMemStats(&memStats)
+	app.Use(func(c *fiber.Ctx) error {
+		return apierror.NotFound("Route not found")
+	})
+
+}
 
-	sqlDB, err := database.GetDatabase().DB()
-	if err != nil {
-		log.Println("Error getting database: ", err)
-	}
-	dbAlive := sqlDB.Ping() == nil
-
-	health := Health{
-		Uptime:        time.Since(startTime).String(),
-		AppVersion:    utils.GetEnv("VERSION"),
-		MemoryUsage:   memStats.Alloc,
-		NumGoroutine:  runtime.NumGoroutine(),
-		NumCPU:        runtime.NumCPU(),
-		DatabaseAlive: dbAlive,
+// requestLogRoutes adjusts the request log policy for routes that don't fit
+// the default. Exports stream whole tables, so their bodies are never worth
+// keeping. Websocket upgrades are rare and carry no body, so every one is
+// recorded.
+func requestLogRoutes(config utils.Config) map[string]middleware.RequestLogPolicy {
+	export := middleware.DefaultRequestLogPolicy(config)
+	export.MaxBodyBytes = 0
+
+	upgrade := middleware.DefaultRequestLogPolicy(config)
+	upgrade.SampleRate = 1
+	upgrade.MaxBodyBytes = 0
+
+	routes := map[string]middleware.RequestLogPolicy{"/ws": upgrade}
+	for _, version := range middleware.APIVersions {
+		routes["/api/"+version+"/admin/requestLogs/export"] = export
 	}
-
-	return c.JSON(health)
+	return routes
 }
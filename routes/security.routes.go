@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/bparsons094/go-server-base/controllers"
+	"github.com/bparsons094/go-server-base/middleware"
+	"github.com/bparsons094/go-server-base/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SecurityRoutes takes CSP violation reports from browsers. It is public, so a
+// fixed per-IP limit keeps a noisy page from filling the table.
+func SecurityRoutes(app *fiber.App, rateLimitStore ratelimit.Store) {
+	reportLimit := ratelimit.Policy{Algorithm: ratelimit.TokenBucket, Limit: 60, Window: time.Minute}
+
+	app.Post(middleware.CSPReportPath, middleware.RateLimit(rateLimitStore, "csp_reports", func() ratelimit.Policy {
+		return reportLimit
+	}), controllers.CreateCSPReport)
+}
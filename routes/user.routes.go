@@ -2,10 +2,15 @@ package routes
 
 import (
 	"github.com/bparsons094/go-server-base/controllers"
+	"github.com/bparsons094/go-server-base/openapi"
 	"github.com/gofiber/fiber/v2"
 )
 
 func UserRoutes(api fiber.Router) {
 	userRoutes := api.Group("/users")
-	userRoutes.Get("/getMe", controllers.GetMe)
+	openapi.Route(userRoutes, fiber.MethodGet, "/getMe", openapi.Operation{
+		Summary:  "Get the logged in user",
+		Tags:     []string{"users"},
+		Response: controllers.MeResponse{},
+	}, controllers.GetMe)
 }
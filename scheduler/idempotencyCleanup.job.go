@@ -0,0 +1,19 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/bparsons094/go-server-base/models"
+	"gorm.io/gorm"
+)
+
+// deleteExpiredIdempotencyKeys prunes stored responses past IDEMPOTENCY_KEY_TTL
+func deleteExpiredIdempotencyKeys(DB *gorm.DB) error {
+	result := DB.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	logger.Debug("Deleted expired idempotency keys", "count", result.RowsAffected)
+	return nil
+}
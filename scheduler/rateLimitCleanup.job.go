@@ -0,0 +1,20 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/bparsons094/go-server-base/ratelimit"
+	"gorm.io/gorm"
+)
+
+// deleteExpiredRateLimits prunes rate_limit_buckets, which only grows when
+// RATE_LIMIT_STORE is postgres
+func deleteExpiredRateLimits(DB *gorm.DB) error {
+	deleted, err := ratelimit.NewPostgresStore(DB).DeleteExpired(DB.Statement.Context, time.Now())
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("Deleted expired rate limit buckets", "count", deleted)
+	return nil
+}
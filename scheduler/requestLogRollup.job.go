@@ -0,0 +1,26 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bparsons094/go-server-base/requestlogs"
+	"gorm.io/gorm"
+)
+
+func rollupRequestLogs(DB *gorm.DB) error {
+	var errs []error
+	for _, granularity := range []requestlogs.Granularity{requestlogs.Hourly, requestlogs.Daily} {
+		start := time.Now()
+		from, to, err := requestlogs.ComputeRollups(DB.Statement.Context, DB, granularity, start)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s rollups: %w", granularity.Name, err))
+			continue
+		}
+
+		logger.Debug("Computed request log rollups", "granularity", granularity.Name, "from", from, "to", to, "duration", time.Since(start))
+	}
+
+	return errors.Join(errs...)
+}
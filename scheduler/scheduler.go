@@ -1,17 +1,84 @@
 package scheduler
 
 import (
-	"log"
+	"context"
+	"errors"
 	"time"
 
+	"github.com/bparsons094/go-server-base/health"
+	"github.com/bparsons094/go-server-base/lifecycle"
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/bparsons094/go-server-base/metrics"
+	"github.com/bparsons094/go-server-base/tracing"
 	"github.com/go-co-op/gocron"
+	"go.opentelemetry.io/otel/codes"
 	"gorm.io/gorm"
 )
 
-func InitScheduler(DB *gorm.DB) {
-	log.Println("Starting Schedules")
+var logger = logging.For("scheduler")
 
+// InitScheduler registers the jobs and a lifecycle hook that starts them. On
+// shutdown it cancels running jobs and waits for them to return. Every job
+// picks up where it left off, so an interrupted run is redone next time.
+func InitScheduler(DB *gorm.DB) {
 	s := gocron.NewScheduler(time.UTC)
+	jobs, cancelJobs := context.WithCancel(context.Background())
+
+	if _, err := s.Every(5).Minutes().SingletonMode().Do(instrument(jobs, "rollup_request_logs", rollupRequestLogs), DB); err != nil {
+		logger.Error("Error scheduling request log rollups", "error", err)
+	}
+
+	if _, err := s.Every(10).Minutes().SingletonMode().Do(instrument(jobs, "delete_expired_rate_limits", deleteExpiredRateLimits), DB); err != nil {
+		logger.Error("Error scheduling rate limit cleanup", "error", err)
+	}
+
+	if _, err := s.Every(15).Minutes().SingletonMode().Do(instrument(jobs, "delete_expired_idempotency_keys", deleteExpiredIdempotencyKeys), DB); err != nil {
+		logger.Error("Error scheduling idempotency key cleanup", "error", err)
+	}
+
+	health.Register("scheduler", func(ctx context.Context) error {
+		if !s.IsRunning() {
+			return errors.New("scheduler is not running")
+		}
+		return nil
+	}, health.Options{})
+
+	lifecycle.Register(lifecycle.Hook{
+		Name:    "scheduler",
+		Order:   lifecycle.OrderScheduler,
+		Timeout: time.Minute,
+		Start: func(ctx context.Context) error {
+			logger.Info("Starting Schedules")
+			s.StartAsync()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancelJobs()
+			s.Stop()
+			return nil
+		},
+	})
+}
+
+// instrument traces every run of a job and records its outcome and duration.
+// Runs get a context derived from jobs, so cancelling it stops their queries.
+func instrument(jobs context.Context, name string, job func(*gorm.DB) error) func(*gorm.DB) {
+	return func(DB *gorm.DB) {
+		if jobs.Err() != nil {
+			return
+		}
+
+		ctx, span := tracing.Tracer().Start(jobs, "job "+name)
+		defer span.End()
+
+		start := time.Now()
+		err := job(DB.WithContext(ctx))
+		metrics.ObserveJob(name, time.Since(start), err)
 
-	s.StartBlocking()
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			logger.ErrorContext(ctx, "Job failed", "job", name, "error", err)
+		}
+	}
 }
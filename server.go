@@ -1,102 +1,265 @@
 package main
 
 import (
-	"log"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"runtime/debug"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/bparsons094/go-server-base/apierror"
+	"github.com/bparsons094/go-server-base/buildinfo"
 	"github.com/bparsons094/go-server-base/controllers"
 	"github.com/bparsons094/go-server-base/database"
+	"github.com/bparsons094/go-server-base/health"
+	"github.com/bparsons094/go-server-base/lifecycle"
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/bparsons094/go-server-base/metrics"
+	"github.com/bparsons094/go-server-base/middleware"
+	"github.com/bparsons094/go-server-base/migrator/migrations"
 	"github.com/bparsons094/go-server-base/routes"
 	"github.com/bparsons094/go-server-base/scheduler"
+	"github.com/bparsons094/go-server-base/tlsconfig"
+	"github.com/bparsons094/go-server-base/tracing"
 	"github.com/bparsons094/go-server-base/utils"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 )
 
 var (
-	server *fiber.App
-	config utils.Config
+	server       *fiber.App
+	config       utils.Config
+	configLoader = utils.ConfigLoader{Path: "./", Args: os.Args[1:]}
+	logger       = logging.For("server")
 )
 
 func init() {
-	log.SetOutput(os.Stdout)
+	config = utils.MustLoadConfig(configLoader)
+	logging.Init(config)
+	logger.Debug("Loaded config", "config", config)
 
-	config = utils.LoadConfig("./")
+	shutdownTracing, err := tracing.Init(config)
+	if err != nil {
+		logging.Fatal(logger, "Error initializing tracing", "error", err)
+	}
+	lifecycle.Register(lifecycle.Hook{Name: "tracing", Order: lifecycle.OrderTracing, Stop: shutdownTracing})
 
 	db := database.ConnectDB(config)
 	controllers.SetDb(db)
 
-	if config.Environment == "local" {
-		server = fiber.New(fiber.Config{
-			ReadBufferSize:    16384,
-			StreamRequestBody: true,
-		})
-	} else {
-		server = fiber.New(fiber.Config{
-			DisableStartupMessage: true,
-			StreamRequestBody:     true,
-			ReadBufferSize:        16384,
-		})
+	if sqlDB, err := db.DB(); err == nil {
+		if err := metrics.RegisterDB(sqlDB, config.DBName); err != nil {
+			logger.Warn("Error registering database metrics", "error", err)
+		}
 	}
 
-	go scheduler.InitScheduler(db)
+	health.Register("database", database.HealthCheck, health.Options{Critical: true})
+	health.Register("migrations", func(ctx context.Context) error {
+		pending, err := migrations.Pending(db.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations: %s", len(pending), strings.Join(pending, ", "))
+		}
+		return nil
+	}, health.Options{Critical: true, CacheTTL: time.Minute})
+
+	fiberConfig := fiber.Config{
+		DisableStartupMessage: config.Environment != "local",
+		StreamRequestBody:     true,
+		ReadBufferSize:        16384,
+		ErrorHandler:          apierror.Handler,
+	}
+
+	// Only trust the proxy header for client IPs when it comes from a known proxy
+	if trustedProxies := utils.SplitList(config.TrustedProxies); len(trustedProxies) > 0 {
+		fiberConfig.EnableTrustedProxyCheck = true
+		fiberConfig.TrustedProxies = trustedProxies
+		fiberConfig.ProxyHeader = config.ProxyHeader
+		fiberConfig.EnableIPValidation = true
+	}
+
+	server = fiber.New(fiberConfig)
+
+	scheduler.InitScheduler(db)
 }
 
 func LoadEnvMiddleware(c *fiber.Ctx) error {
-	c.Locals("clientOrigin", config.ClientOrigin)
+	c.Locals("clientOrigin", utils.GetConfig().ClientOrigin)
 	return c.Next()
 }
 
 func main() {
-	server.Use(cors.New(cors.Config{
-		AllowOrigins:     config.ClientOrigin,
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
-		AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
-		AllowCredentials: true,
-	}))
+	server.Use(middleware.RequestID)
+	server.Use(middleware.Tracing)
+	server.Use(middleware.Metrics)
+	server.Use(middleware.QueryStats)
+	cors := middleware.NewCORS(middleware.DefaultCORSConfig(config))
+	utils.SubscribeConfig(func(previous, current utils.Config) {
+		cors.Update(middleware.DefaultCORSConfig(current))
+	})
+	server.Use(cors.Handler)
+	server.Use(middleware.SecurityHeaders(middleware.DefaultSecurityHeadersConfig(config)))
 	server.Use(LoadEnvMiddleware)
-	if config.Environment == "local" {
-		server.Use(logger.New())
-	}
-	server.Use(recover.New())
-	server.Use(func(c *fiber.Ctx) error {
-		c.Set("Access-Control-Allow-Origin", config.ClientOrigin)
-		c.Set("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
-		c.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Set("Access-Control-Allow-Credentials", "true")
-
-		// Handle Preflight Request
-		if c.Method() == "OPTIONS" {
-			c.Status(fiber.StatusNoContent)
+	server.Use(middleware.AccessLog)
+	server.Use(recover.New(recover.Config{
+		EnableStackTrace: true,
+		StackTraceHandler: func(c *fiber.Ctx, recovered interface{}) {
+			logger.ErrorContext(logging.Context(c), "Recovered from panic", "panic", recovered, "stack", string(debug.Stack()))
+		},
+	}))
+
+	routes.SetupRoutes(server, config)
+
+	// Config and certificate watchers stop before the server drains
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	lifecycle.Register(lifecycle.Hook{
+		Name:  "file_watchers",
+		Order: lifecycle.OrderFileWatchers,
+		Start: func(ctx context.Context) error {
+			go watchConfig(watchCtx)
 			return nil
-		}
+		},
+		Stop: func(ctx context.Context) error {
+			stopWatching()
+			return nil
+		},
+	})
 
-		return c.Next()
+	listenErr := make(chan error, 2)
+	lifecycle.Register(lifecycle.Hook{
+		Name:  "http",
+		Order: lifecycle.OrderHTTPServer,
+		// Half the budget, the hooks after it need time to drain too
+		Timeout: config.ShutdownTimeout / 2,
+		Start: func(ctx context.Context) error {
+			listener, err := newListener(watchCtx)
+			if err != nil {
+				return err
+			}
+			go func() {
+				listenErr <- server.Listener(listener)
+			}()
+			return nil
+		},
+		Stop: server.ShutdownWithContext,
 	})
 
-	routes.SetupRoutes(server, config)
+	if config.TLSRedirectPort != "" {
+		redirectServer := tlsconfig.RedirectServer(":"+config.TLSRedirectPort, config.Port)
+		lifecycle.Register(lifecycle.Hook{
+			Name:  "https_redirect",
+			Order: lifecycle.OrderHTTPServer,
+			Start: func(ctx context.Context) error {
+				go func() {
+					if err := redirectServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						listenErr <- err
+					}
+				}()
+				return nil
+			},
+			Stop: redirectServer.Shutdown,
+		})
+	}
 
-	// Creates a channel to listen for a shutdown signal
-	go setupGracefulShutdown(server)
+	if err := lifecycle.Start(context.Background()); err != nil {
+		logging.Fatal(logger, "Error starting", "error", err)
+	}
+	logger.Info("Server is running", append([]any{"environment", config.Environment, "port", config.Port, "tls", config.TLSEnabled}, buildinfo.Get().LogAttrs()...)...)
 
-	log.Println("Server is running in", config.Environment, "mode on port", config.Port)
-	log.Fatal(server.Listen(":" + config.Port))
+	os.Exit(waitForShutdown(listenErr))
 }
 
-func setupGracefulShutdown(server *fiber.App) {
-	channel := make(chan os.Signal, 1)
-	signal.Notify(channel, os.Interrupt, syscall.SIGTERM)
+// newListener listens on PORT, wrapped in TLS when TLS_ENABLED is set. The
+// certificate is reloaded whenever its files change until watchCtx is done.
+func newListener(watchCtx context.Context) (net.Listener, error) {
+	listener, err := net.Listen("tcp", ":"+config.Port)
+	if err != nil || !config.TLSEnabled {
+		return listener, err
+	}
 
-	go func() {
-		<-channel
-		log.Println("Received termination signal, gracefully shutting down...")
+	reloader, err := tlsconfig.NewCertificateReloader(config.TLSCertFile, config.TLSKeyFile)
+	if err == nil {
+		var tlsConfig *tls.Config
+		if tlsConfig, err = tlsconfig.New(config, reloader); err == nil {
+			listener = tls.NewListener(listener, tlsConfig)
+		}
+	}
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
 
-		if err := server.Shutdown(); err != nil {
-			log.Fatal("Server forced to shutdown:", err)
+	go func() {
+		err := reloader.Watch(watchCtx, func(err error) {
+			if err != nil {
+				logger.Error("Error reloading TLS certificate", "error", err)
+				return
+			}
+			logger.Info("Reloaded TLS certificate")
+		})
+		if err != nil {
+			logger.Error("TLS certificate watcher stopped", "error", err)
 		}
 	}()
+	return listener, nil
+}
+
+// waitForShutdown blocks until a termination signal or a listener failure,
+// then stops every subsystem in order and returns the process exit code
+func waitForShutdown(listenErr <-chan error) int {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	exitCode := 0
+	select {
+	case received := <-signals:
+		logger.Info("Received termination signal, gracefully shutting down...", "signal", received.String())
+	case err := <-listenErr:
+		logger.Error("Server stopped", "error", err)
+		exitCode = 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	if err := lifecycle.Stop(ctx); err != nil {
+		logger.Error("Shutdown did not complete cleanly", "error", err)
+		exitCode = 1
+	}
+
+	logger.Info("Shutdown complete", "exit_code", exitCode)
+	return exitCode
+}
+
+// watchConfig applies reloadable settings on SIGHUP or when a config file
+// changes. Invalid configs are rejected and the running config is kept.
+func watchConfig(ctx context.Context) {
+	err := utils.WatchConfig(ctx, configLoader, func(result utils.ReloadResult, err error) {
+		if errors.Is(err, utils.ErrConfigWatch) {
+			logger.Error("Config file watcher failed, reload with SIGHUP instead", "error", err)
+			return
+		}
+		if err != nil {
+			logger.Error("Rejected config reload", "error", err)
+			return
+		}
+		if len(result.Ignored) > 0 {
+			logger.Warn("Config changes need a restart to take effect", "keys", result.Ignored)
+		}
+		if len(result.Changed) > 0 {
+			logger.Info("Config reloaded", "keys", result.Changed)
+		}
+	})
+	if err != nil {
+		logger.Error("Config watcher stopped", "error", err)
+	}
 }
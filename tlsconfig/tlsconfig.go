@@ -0,0 +1,130 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bparsons094/go-server-base/metrics"
+	"github.com/bparsons094/go-server-base/utils"
+)
+
+var versions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// CertificateReloader serves the key pair from disk and swaps in a new one
+// when the files change, so certificates rotate without a restart
+type CertificateReloader struct {
+	certFile    string
+	keyFile     string
+	certificate atomic.Pointer[tls.Certificate]
+}
+
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	reloader := &CertificateReloader{certFile: certFile, keyFile: keyFile}
+	if err := reloader.Reload(); err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// Reload loads the key pair, keeping the current one when the files are
+// invalid, such as halfway through a rotation
+func (r *CertificateReloader) Reload() error {
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS key pair: %w", err)
+	}
+
+	if leaf, err := x509.ParseCertificate(certificate.Certificate[0]); err == nil {
+		certificate.Leaf = leaf
+		metrics.TLSCertificateExpiry.Set(float64(leaf.NotAfter.Unix()))
+	}
+
+	r.certificate.Store(&certificate)
+	return nil
+}
+
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.certificate.Load(), nil
+}
+
+// Watch reloads whenever the certificate or key changes until ctx is done
+func (r *CertificateReloader) Watch(ctx context.Context, onReload func(error)) error {
+	return utils.WatchFiles(ctx, []string{r.certFile, r.keyFile}, func() {
+		onReload(r.Reload())
+	}, onReload)
+}
+
+// New builds the server TLS config from TLS_MIN_VERSION and TLS_CIPHER_SUITES
+// with certificates served by reloader
+func New(config utils.Config, reloader *CertificateReloader) (*tls.Config, error) {
+	minVersion, ok := versions[config.TLSMinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported TLS_MIN_VERSION %q", config.TLSMinVersion)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+		NextProtos:     []string{"http/1.1"},
+	}
+
+	// TLS 1.3 suites are not configurable in Go, so this only affects 1.2
+	if names := utils.SplitList(config.TLSCipherSuites); len(names) > 0 {
+		suites, err := CipherSuites(names)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.CipherSuites = suites
+	}
+
+	return tlsConfig, nil
+}
+
+// CipherSuites maps IANA names such as TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+// to their IDs, rejecting suites Go considers insecure
+func CipherSuites(names []string) ([]uint16, error) {
+	available := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		available[suite.Name] = suite.ID
+	}
+
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := available[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// RedirectServer answers plain HTTP on addr with a permanent redirect to the
+// same URL on the HTTPS port
+func RedirectServer(addr string, httpsPort string) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: 5 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := r.Host
+			if hostname, _, err := net.SplitHostPort(host); err == nil {
+				host = hostname
+			}
+			if httpsPort != "443" {
+				host = net.JoinHostPort(host, httpsPort)
+			}
+
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+		}),
+	}
+}
@@ -1,100 +1,156 @@
 package utils
 
 import (
+	"errors"
 	"log"
 	"os"
-	"reflect"
-	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
-
-	"github.com/joho/godotenv"
 )
 
+// Config is populated by ConfigLoader from the mapstructure keys. Fields
+// without a default are required unless tagged optional, and validate rules
+// are checked once every source has been applied. Fields tagged reload are
+// swapped in by ReloadConfig, the rest need a restart. Fields tagged secret
+// are redacted when the config is printed or logged.
 type Config struct {
-	Version               string        `mapstructure:"VERSION"`
-	Environment           string        `mapstructure:"ENVIRONMENT"`
-	Port                  string        `mapstructure:"PORT"`
-	ClientOrigin          string        `mapstructure:"CLIENT_ORIGIN"`
-	DBHost                string        `mapstructure:"DB_HOST"`
-	DBUser                string        `mapstructure:"DB_USER"`
-	DBPassword            string        `mapstructure:"DB_PASSWORD"`
-	DBName                string        `mapstructure:"DB_NAME"`
-	DBPort                string        `mapstructure:"DB_PORT"`
-	DBLogging             string        `mapstructure:"DB_LOGGING"`
-	AccessTokenPrivateKey string        `mapstructure:"ACCESS_TOKEN_PRIVATE_KEY"`
-	AccessTokenPublicKey  string        `mapstructure:"ACCESS_TOKEN_PUBLIC_KEY"`
-	AccessTokenExpiresIn  time.Duration `mapstructure:"ACCESS_TOKEN_EXPIRES_IN"`
-	AccessTokenMaxAge     int           `mapstructure:"ACCESS_TOKEN_MAX_AGE"`
+	Version         string        `mapstructure:"VERSION" optional:"true"`
+	Environment     string        `mapstructure:"ENVIRONMENT"`
+	Port            string        `mapstructure:"PORT" validate:"port"`
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT" default:"30s" validate:"min=1s"`
+	// Serves HTTPS on PORT when enabled, see validateConfig for the files
+	TLSEnabled      bool   `mapstructure:"TLS_ENABLED" default:"false"`
+	TLSCertFile     string `mapstructure:"TLS_CERT_FILE" optional:"true"`
+	TLSKeyFile      string `mapstructure:"TLS_KEY_FILE" optional:"true"`
+	TLSMinVersion   string `mapstructure:"TLS_MIN_VERSION" default:"1.2" validate:"oneof=1.2 1.3"`
+	TLSCipherSuites string `mapstructure:"TLS_CIPHER_SUITES" optional:"true"`
+	// Plain HTTP port that redirects to HTTPS, off when empty
+	TLSRedirectPort string `mapstructure:"TLS_REDIRECT_PORT" optional:"true" validate:"port"`
+	ClientOrigin    string `mapstructure:"CLIENT_ORIGIN" validate:"url" reload:"true"`
+	// Comma separated, wildcard subdomains like https://*.example.com are
+	// allowed. Defaults to CLIENT_ORIGIN.
+	CORSAllowedOrigins       string        `mapstructure:"CORS_ALLOWED_ORIGINS" optional:"true" reload:"true"`
+	CORSMaxAge               time.Duration `mapstructure:"CORS_MAX_AGE" default:"10m" validate:"min=0" reload:"true"`
+	DBHost                   string        `mapstructure:"DB_HOST"`
+	DBUser                   string        `mapstructure:"DB_USER"`
+	DBPassword               string        `mapstructure:"DB_PASSWORD" secret:"true"`
+	DBName                   string        `mapstructure:"DB_NAME"`
+	DBPort                   string        `mapstructure:"DB_PORT" validate:"port"`
+	DBLogging                string        `mapstructure:"DB_LOGGING" default:"warn" validate:"oneof=info warn" reload:"true"`
+	DBSlowQueryThreshold     time.Duration `mapstructure:"DB_SLOW_QUERY_THRESHOLD" default:"200ms" validate:"min=0"`
+	DBRepeatedQueryThreshold int           `mapstructure:"DB_REPEATED_QUERY_THRESHOLD" default:"10" validate:"min=0"`
+	AccessTokenPrivateKey    string        `mapstructure:"ACCESS_TOKEN_PRIVATE_KEY" secret:"true"`
+	AccessTokenPublicKey     string        `mapstructure:"ACCESS_TOKEN_PUBLIC_KEY"`
+	AccessTokenExpiresIn     time.Duration `mapstructure:"ACCESS_TOKEN_EXPIRES_IN" validate:"min=1s"`
+	AccessTokenMaxAge        int           `mapstructure:"ACCESS_TOKEN_MAX_AGE" validate:"min=1"`
+	LogLevel                 string        `mapstructure:"LOG_LEVEL" default:"info" validate:"oneof=debug info warn error" reload:"true"`
+	LogLevels                string        `mapstructure:"LOG_LEVELS" optional:"true" reload:"true"`
+	LogFormat                string        `mapstructure:"LOG_FORMAT" optional:"true" validate:"oneof=json text"`
+	TrustedProxies           string        `mapstructure:"TRUSTED_PROXIES" optional:"true"`
+	ProxyHeader              string        `mapstructure:"PROXY_HEADER" default:"X-Forwarded-For"`
+	RequestLogSampleRate     float64       `mapstructure:"REQUEST_LOG_SAMPLE_RATE" default:"1" validate:"min=0,max=1"`
+	RequestLogMaxBodyBytes   int           `mapstructure:"REQUEST_LOG_MAX_BODY_BYTES" default:"16384" validate:"min=0"`
+	RequestLogContentTypes   string        `mapstructure:"REQUEST_LOG_CONTENT_TYPES" default:"application/json,application/x-www-form-urlencoded,application/xml,text/"`
+	TracingExporter          string        `mapstructure:"TRACING_EXPORTER" default:"none" validate:"oneof=none stdout otlp"`
+	TracingServiceName       string        `mapstructure:"TRACING_SERVICE_NAME" default:"go-server-base"`
+	TracingSampleRatio       float64       `mapstructure:"TRACING_SAMPLE_RATIO" default:"1" validate:"min=0,max=1"`
+	// Defaults to true in local, see applyDerivedDefaults
+	DebugEndpointsEnabled bool   `mapstructure:"DEBUG_ENDPOINTS_ENABLED" default:"false"`
+	DebugToken            string `mapstructure:"DEBUG_TOKEN" optional:"true" secret:"true"`
+	// Bearer token Prometheus scrapes /metrics with, admins and DEBUG_TOKEN work too
+	MetricsToken string `mapstructure:"METRICS_TOKEN" optional:"true" secret:"true"`
+	// Keys service clients send in X-API-Key, rate limited per key
+	APIKeys            string        `mapstructure:"API_KEYS" optional:"true" secret:"true" reload:"true"`
+	HSTSMaxAge         time.Duration `mapstructure:"HSTS_MAX_AGE" default:"4320h" validate:"min=0"`
+	CSPReportOnly      bool          `mapstructure:"CSP_REPORT_ONLY" default:"false"`
+	RateLimitStore     string        `mapstructure:"RATE_LIMIT_STORE" default:"memory" validate:"oneof=memory postgres"`
+	RateLimitAlgorithm string        `mapstructure:"RATE_LIMIT_ALGORITHM" default:"sliding_window" validate:"oneof=sliding_window token_bucket" reload:"true"`
+	// Requests per window for each route group, 0 disables the limit
+	RateLimitAPIRequests int           `mapstructure:"RATE_LIMIT_API_REQUESTS" default:"300" validate:"min=0" reload:"true"`
+	RateLimitAPIWindow   time.Duration `mapstructure:"RATE_LIMIT_API_WINDOW" default:"1m" validate:"min=1s" reload:"true"`
+	// Applies per IP before authentication, so it also covers requests with bad tokens
+	RateLimitIPRequests        int           `mapstructure:"RATE_LIMIT_IP_REQUESTS" default:"600" validate:"min=0" reload:"true"`
+	RateLimitIPWindow          time.Duration `mapstructure:"RATE_LIMIT_IP_WINDOW" default:"1m" validate:"min=1s" reload:"true"`
+	RateLimitWebSocketRequests int           `mapstructure:"RATE_LIMIT_WS_REQUESTS" default:"30" validate:"min=0" reload:"true"`
+	RateLimitWebSocketWindow   time.Duration `mapstructure:"RATE_LIMIT_WS_WINDOW" default:"1m" validate:"min=1s" reload:"true"`
+	// Defaults to true outside production, see applyDerivedDefaults
+	APIDocsEnabled bool `mapstructure:"API_DOCS_ENABLED" default:"false"`
+	// Version served for /api requests without a version in the path or API-Version header
+	APIDefaultVersion string `mapstructure:"API_DEFAULT_VERSION" default:"v1" validate:"oneof=v1 v2" reload:"true"`
+	// How long responses are replayed, and how long a running request holds its key
+	IdempotencyKeyTTL      time.Duration `mapstructure:"IDEMPOTENCY_KEY_TTL" default:"24h" validate:"min=1m"`
+	IdempotencyLockTimeout time.Duration `mapstructure:"IDEMPOTENCY_LOCK_TIMEOUT" default:"1m" validate:"min=1s"`
 }
 
-var configInstance Config
+var configInstance atomic.Pointer[Config]
 
+// LoadConfig loads the config from path's dotenv files, the environment and
+// CONFIG_FILE, exiting with every problem listed when it is invalid
 func LoadConfig(path string) Config {
-	err := godotenv.Load(path + "local.env")
-	if err != nil {
-		log.Println("Could not load .env.local file:", err)
-		err = godotenv.Load(path + ".env")
-		if err != nil {
-			log.Println("No .env* files found, moving forward with host env")
-		}
-	}
+	return MustLoadConfig(ConfigLoader{Path: path})
+}
 
-	AccessTokenExpires, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_EXPIRES_IN"))
+func MustLoadConfig(loader ConfigLoader) Config {
+	config, err := loader.Load()
 	if err != nil {
-		log.Fatal("Error parsing ACCESS_TOKEN_EXPIRED_IN")
+		log.Fatalf("Shutting Down. Invalid configuration:\n%v", err)
 	}
-	AccessTokenAge, err := strconv.Atoi(os.Getenv("ACCESS_TOKEN_MAX_AGE"))
-	if err != nil {
-		log.Fatal("Error parsing ACCESS_TOKEN_MAX_AGE")
-	}
-
-	config := Config{
-		Version:               os.Getenv("VERSION"),
-		Environment:           os.Getenv("ENVIRONMENT"),
-		Port:                  os.Getenv("PORT"),
-		ClientOrigin:          os.Getenv("CLIENT_ORIGIN"),
-		DBHost:                os.Getenv("DB_HOST"),
-		DBUser:                os.Getenv("DB_USER"),
-		DBPassword:            os.Getenv("DB_PASSWORD"),
-		DBName:                os.Getenv("DB_NAME"),
-		DBPort:                os.Getenv("DB_PORT"),
-		DBLogging:             os.Getenv("DB_LOGGING"),
-		AccessTokenPrivateKey: os.Getenv("ACCESS_TOKEN_PRIVATE_KEY"),
-		AccessTokenPublicKey:  os.Getenv("ACCESS_TOKEN_PUBLIC_KEY"),
-		AccessTokenExpiresIn:  AccessTokenExpires,
-		AccessTokenMaxAge:     AccessTokenAge,
-	}
-
-	testEnvsAreSet(config)
 
 	SetConfig(config)
 	return config
 }
 
 func SetConfig(config Config) {
-	configInstance = config
+	configInstance.Store(&config)
 }
 
+// GetConfig returns the current config, including reloaded values
 func GetConfig() Config {
-	return configInstance
+	if config := configInstance.Load(); config != nil {
+		return *config
+	}
+	return Config{}
 }
 
 func GetEnv(value string) string {
 	return os.Getenv(value)
 }
 
-func testEnvsAreSet(config Config) {
-	var unsetEnvs []string
-
-	value := reflect.ValueOf(config)
+// SplitList splits a comma separated config value, dropping empty entries
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
 
-	for i := 0; i < value.NumField(); i++ {
-		if value.Field(i).Interface() == "" || (value.Field(i).Kind() == reflect.Int && value.Field(i).Int() == 0) {
-			unsetEnvs = append(unsetEnvs, value.Type().Field(i).Tag.Get("mapstructure"))
+// validateConfig checks rules that span several fields
+func validateConfig(config Config) []error {
+	var errs []error
+	if config.TLSEnabled && (config.TLSCertFile == "" || config.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE and TLS_KEY_FILE are required when TLS_ENABLED is true"))
+	}
+	if config.TLSRedirectPort != "" && !config.TLSEnabled {
+		errs = append(errs, errors.New("TLS_REDIRECT_PORT needs TLS_ENABLED"))
+	}
+	for _, origin := range SplitList(config.CORSAllowedOrigins) {
+		if origin == "*" {
+			errs = append(errs, errors.New("CORS_ALLOWED_ORIGINS can't be * since the API allows credentials, list the origins instead"))
 		}
 	}
+	return errs
+}
 
-	if len(unsetEnvs) > 0 {
-		log.Fatalf("Shutting Down. The following environment variables are not set: %v", unsetEnvs)
+// applyDerivedDefaults fills defaults that depend on other fields
+func applyDerivedDefaults(config *Config, values map[string]configValue) {
+	if values["DEBUG_ENDPOINTS_ENABLED"].value == "" {
+		config.DebugEndpointsEnabled = config.Environment == "local"
+	}
+	if values["API_DOCS_ENABLED"].value == "" {
+		config.APIDocsEnabled = config.Environment != "production"
 	}
 }
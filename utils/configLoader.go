@@ -0,0 +1,473 @@
+package utils
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SourceDefault = "default"
+	SourceDotenv  = "dotenv"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceSecret  = "secret provider"
+	SourceFlag    = "flag"
+
+	// Appended to any key to read its value from a file instead, e.g.
+	// DB_PASSWORD_FILE=/run/secrets/db_password
+	fileSuffix = "_FILE"
+)
+
+// ConfigLoader reads Config from layered sources, each overriding the one
+// before: tag defaults, local.env (or .env when it is missing), the process
+// environment, the secret provider, a YAML or TOML file and finally
+// command-line flags.
+type ConfigLoader struct {
+	// Directory holding local.env or .env
+	Path string
+	// YAML or TOML file, falls back to the -config flag and then CONFIG_FILE
+	File string
+	// Command-line arguments, every key can be set as a flag, e.g. -db-host
+	Args []string
+	// Consulted for every key, defaults to a FileSecretProvider on SECRETS_DIR
+	Secrets SecretProvider
+}
+
+// configValue is the value a key was last set to. An empty value still
+// overrides lower layers, so a key set in local.env can be cleared from the
+// environment, and then decodes as if it was never set.
+type configValue struct {
+	value  string
+	source string
+}
+
+type configField struct {
+	index    int
+	key      string
+	fallback *string
+	optional bool
+	// Tagged secret:"true", redacted whenever the config is printed
+	secret bool
+	// Tagged reload:"true", applied by ReloadConfig without a restart
+	reloadable bool
+	rules      []string
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load applies every source and returns all missing and invalid keys
+// together rather than stopping at the first one
+func (l ConfigLoader) Load() (Config, error) {
+	var config Config
+
+	values, errs := l.values()
+	errs = append(errs, decodeConfig(&config, values)...)
+	errs = append(errs, validateConfig(config)...)
+	if len(errs) > 0 {
+		return config, errors.Join(errs...)
+	}
+
+	applyDerivedDefaults(&config, values)
+	return config, nil
+}
+
+func (l ConfigLoader) values() (map[string]configValue, []error) {
+	var errs []error
+	fields := configFields()
+	known := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		known[field.key] = true
+	}
+
+	values := map[string]configValue{}
+	set := func(key, value, source string) {
+		values[key] = configValue{value: value, source: source}
+	}
+
+	// KEY_FILE is read at the same layer as KEY, so setting both is ambiguous
+	setLayer := func(lookup func(string) (string, bool), source string) {
+		for _, field := range fields {
+			value, hasValue := lookup(field.key)
+			file, _ := lookup(field.key + fileSuffix)
+			if file == "" {
+				if hasValue {
+					set(field.key, value, source)
+				}
+				continue
+			}
+			if value != "" {
+				errs = append(errs, fmt.Errorf("%s and %s are both set in %s", field.key, field.key+fileSuffix, source))
+				continue
+			}
+
+			content, err := readSecretFile(file)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s (from %s): %w", field.key+fileSuffix, source, err))
+				continue
+			}
+			set(field.key, content, source+" "+field.key+fileSuffix)
+		}
+	}
+
+	dotenv := readDotenv(l.Path)
+	setLayer(func(key string) (string, bool) {
+		value, ok := dotenv[key]
+		return value, ok
+	}, SourceDotenv)
+	setLayer(os.LookupEnv, SourceEnv)
+
+	secrets := l.Secrets
+	if dir := firstNonEmpty(os.Getenv("SECRETS_DIR"), dotenv["SECRETS_DIR"]); secrets == nil && dir != "" {
+		secrets = FileSecretProvider{Dir: dir}
+	}
+	if secrets != nil {
+		for _, field := range fields {
+			value, ok, err := secrets.Secret(field.key)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s (from %s): %w", field.key, SourceSecret, err))
+				continue
+			}
+			if ok {
+				set(field.key, value, SourceSecret)
+			}
+		}
+	}
+
+	flags, configFlag, err := parseConfigFlags(fields, l.Args)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	if file := l.configFile(configFlag, dotenv); file != "" {
+		fileValues, err := readConfigFile(file)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		keys := make([]string, 0, len(fileValues))
+		for key := range fileValues {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if fieldKey, isFile := strings.CutSuffix(key, fileSuffix); isFile && known[fieldKey] {
+				if fileValues[key] == "" {
+					continue
+				}
+				content, err := readSecretFile(fileValues[key])
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s (from %s): %w", key, SourceFile, err))
+					continue
+				}
+				set(fieldKey, content, SourceFile+" "+key)
+				continue
+			}
+			if !known[key] {
+				errs = append(errs, fmt.Errorf("%s: unknown key %s", file, key))
+				continue
+			}
+			set(key, fileValues[key], SourceFile)
+		}
+	}
+
+	for key, value := range flags {
+		set(key, value, SourceFlag)
+	}
+
+	return values, errs
+}
+
+// WatchedFiles lists the dotenv and config files Load reads from
+func (l ConfigLoader) WatchedFiles() []string {
+	files := []string{l.Path + "local.env", l.Path + ".env"}
+
+	_, configFlag, _ := parseConfigFlags(configFields(), l.Args)
+	dotenv, _ := godotenv.Read(l.Path + "local.env")
+	if dotenv == nil {
+		dotenv, _ = godotenv.Read(l.Path + ".env")
+	}
+	if file := l.configFile(configFlag, dotenv); file != "" {
+		files = append(files, file)
+	}
+	return files
+}
+
+func (l ConfigLoader) configFile(configFlag string, dotenv map[string]string) string {
+	return firstNonEmpty(l.File, configFlag, os.Getenv("CONFIG_FILE"), dotenv["CONFIG_FILE"])
+}
+
+func configFields() []configField {
+	configType := reflect.TypeOf(Config{})
+
+	fields := make([]configField, 0, configType.NumField())
+	for i := 0; i < configType.NumField(); i++ {
+		structField := configType.Field(i)
+		key := structField.Tag.Get("mapstructure")
+		if key == "" {
+			continue
+		}
+
+		field := configField{
+			index:      i,
+			key:        key,
+			optional:   structField.Tag.Get("optional") == "true",
+			reloadable: structField.Tag.Get("reload") == "true",
+			secret:     structField.Tag.Get("secret") == "true",
+		}
+		if fallback, ok := structField.Tag.Lookup("default"); ok {
+			field.fallback = &fallback
+		}
+		if rules := structField.Tag.Get("validate"); rules != "" {
+			field.rules = strings.Split(rules, ",")
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func decodeConfig(config *Config, values map[string]configValue) []error {
+	var errs []error
+	target := reflect.ValueOf(config).Elem()
+
+	for _, field := range configFields() {
+		value, ok := values[field.key]
+		if !ok || value.value == "" {
+			switch {
+			case field.fallback != nil:
+				value = configValue{value: *field.fallback, source: SourceDefault}
+			case field.optional:
+				continue
+			default:
+				errs = append(errs, fmt.Errorf("%s is required", field.key))
+				continue
+			}
+		}
+
+		fieldValue := target.Field(field.index)
+		if err := setConfigField(fieldValue, value.value); err != nil {
+			if field.secret {
+				err = fmt.Errorf("invalid value for a %s", fieldValue.Type())
+			}
+			errs = append(errs, fmt.Errorf("%s (from %s): %w", field.key, value.source, err))
+			continue
+		}
+
+		for _, rule := range field.rules {
+			if err := checkConfigRule(fieldValue, rule); err != nil {
+				errs = append(errs, fmt.Errorf("%s (from %s): %w", field.key, value.source, err))
+			}
+		}
+	}
+
+	return errs
+}
+
+func setConfigField(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("expected a duration such as 30s or 15m, got %q", value)
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", value)
+		}
+		field.SetBool(parsed)
+	case reflect.Int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", value)
+		}
+		field.SetInt(int64(parsed))
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", value)
+		}
+		field.SetFloat(parsed)
+	default:
+		return fmt.Errorf("unsupported config field type %s", field.Type())
+	}
+	return nil
+}
+
+// checkConfigRule supports port, url, oneof=a b c, min=n and max=n. Bounds on
+// durations are durations themselves, e.g. min=1s.
+func checkConfigRule(field reflect.Value, rule string) error {
+	name, argument, _ := strings.Cut(rule, "=")
+
+	switch name {
+	case "port":
+		port, err := strconv.Atoi(field.String())
+		if err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("expected a port between 1 and 65535, got %q", field.String())
+		}
+	case "url":
+		parsed, err := url.Parse(field.String())
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("expected an absolute URL, got %q", field.String())
+		}
+	case "oneof":
+		allowed := strings.Fields(argument)
+		for _, option := range allowed {
+			if field.String() == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected one of %s, got %q", strings.Join(allowed, ", "), field.String())
+	case "min", "max":
+		value, bound, err := configRuleBound(field, argument)
+		if err != nil {
+			return err
+		}
+		if name == "min" && value < bound {
+			return fmt.Errorf("must be at least %s", argument)
+		}
+		if name == "max" && value > bound {
+			return fmt.Errorf("must be at most %s", argument)
+		}
+	default:
+		return fmt.Errorf("unknown validation rule %q", rule)
+	}
+	return nil
+}
+
+func configRuleBound(field reflect.Value, argument string) (value float64, bound float64, err error) {
+	if field.Type() == durationType {
+		duration, err := time.ParseDuration(argument)
+		return float64(field.Int()), float64(duration), err
+	}
+
+	bound, err = strconv.ParseFloat(argument, 64)
+	switch field.Kind() {
+	case reflect.Int:
+		return float64(field.Int()), bound, err
+	case reflect.Float64:
+		return field.Float(), bound, err
+	}
+	return 0, 0, fmt.Errorf("min and max only apply to numbers and durations")
+}
+
+func readDotenv(path string) map[string]string {
+	values, err := godotenv.Read(path + "local.env")
+	if err != nil {
+		log.Println("Could not load .env.local file:", err)
+		values, err = godotenv.Read(path + ".env")
+		if err != nil {
+			log.Println("No .env* files found, moving forward with host env")
+		}
+	}
+	return values
+}
+
+// configFlagName turns DB_HOST into db-host
+func configFlagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+func parseConfigFlags(fields []configField, args []string) (values map[string]string, configFile string, err error) {
+	flagSet := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	file := flagSet.String("config", "", "YAML or TOML config file")
+
+	keysByFlag := make(map[string]string, len(fields))
+	for _, field := range fields {
+		name := configFlagName(field.key)
+		keysByFlag[name] = field.key
+		flagSet.String(name, "", "overrides "+field.key)
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return nil, "", err
+	}
+
+	values = map[string]string{}
+	flagSet.Visit(func(f *flag.Flag) {
+		if key, ok := keysByFlag[f.Name]; ok {
+			values[key] = f.Value.String()
+		}
+	})
+	return values, *file, nil
+}
+
+// readConfigFile flattens the file into config keys, so nested sections such
+// as db: {host: x} and top level DB_HOST: x both set DB_HOST
+func readConfigFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	case ".toml":
+		err = toml.Unmarshal(content, &raw)
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flattenConfigFile("", raw, values)
+	return values, nil
+}
+
+func flattenConfigFile(prefix string, raw map[string]any, values map[string]string) {
+	for key, value := range raw {
+		key = strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch typed := value.(type) {
+		case map[string]any:
+			flattenConfigFile(key, typed, values)
+		case []any:
+			items := make([]string, len(typed))
+			for i, item := range typed {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+			// "key:" with no value clears the key like an empty string
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(typed)
+		}
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
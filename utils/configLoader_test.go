@@ -0,0 +1,229 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var requiredConfig = map[string]string{
+	"ENVIRONMENT":              "test",
+	"PORT":                     "8000",
+	"CLIENT_ORIGIN":            "http://localhost:3000",
+	"DB_HOST":                  "localhost",
+	"DB_USER":                  "postgres",
+	"DB_PASSWORD":              "postgres",
+	"DB_NAME":                  "app",
+	"DB_PORT":                  "5432",
+	"ACCESS_TOKEN_PRIVATE_KEY": "private",
+	"ACCESS_TOKEN_PUBLIC_KEY":  "public",
+	"ACCESS_TOKEN_EXPIRES_IN":  "15m",
+	"ACCESS_TOKEN_MAX_AGE":     "15",
+}
+
+// isolateEnv unsets every config key for the test, so the host environment
+// can't leak into the layers under test
+func isolateEnv(t *testing.T) {
+	t.Helper()
+
+	keys := []string{"CONFIG_FILE", "SECRETS_DIR"}
+	for _, field := range configFields() {
+		keys = append(keys, field.key, field.key+fileSuffix)
+	}
+
+	for _, key := range keys {
+		if value, ok := os.LookupEnv(key); ok {
+			os.Unsetenv(key)
+			t.Cleanup(func() { os.Setenv(key, value) })
+		}
+	}
+}
+
+// testLoader writes a local.env holding the required keys plus dotenv
+func testLoader(t *testing.T, dotenv map[string]string) ConfigLoader {
+	t.Helper()
+	isolateEnv(t)
+
+	lines := []string{}
+	for key, value := range requiredConfig {
+		if _, overridden := dotenv[key]; !overridden {
+			lines = append(lines, key+"="+value)
+		}
+	}
+	for key, value := range dotenv {
+		lines = append(lines, key+"="+value)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "local.env"), []byte(strings.Join(lines, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return ConfigLoader{Path: dir + string(filepath.Separator)}
+}
+
+func TestConfigLoaderPrecedence(t *testing.T) {
+	loader := testLoader(t, map[string]string{
+		"PORT":    "1000",
+		"DB_HOST": "dotenv",
+		"DB_NAME": "dotenv",
+		"DB_USER": "dotenv",
+	})
+	t.Setenv("DB_HOST", "env")
+	t.Setenv("DB_NAME", "env")
+	t.Setenv("DB_USER", "env")
+
+	loader.File = filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(loader.File, []byte("db:\n  name: file\n  user: file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loader.Args = []string{"-db-user", "flag"}
+
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key  string
+		got  string
+		want string
+	}{
+		{"PORT", config.Port, "1000"},
+		{"DB_HOST", config.DBHost, "env"},
+		{"DB_NAME", config.DBName, "file"},
+		{"DB_USER", config.DBUser, "flag"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("%s = %q, want %q", test.key, test.got, test.want)
+		}
+	}
+}
+
+func TestConfigLoaderEmptyValueClearsLowerLayers(t *testing.T) {
+	loader := testLoader(t, map[string]string{
+		"ENVIRONMENT":          "local",
+		"LOG_LEVELS":           "http=debug",
+		"TRACING_SERVICE_NAME": "from-dotenv",
+		"API_DOCS_ENABLED":     "false",
+	})
+	t.Setenv("LOG_LEVELS", "")
+	t.Setenv("API_DOCS_ENABLED", "")
+	loader.Args = []string{"-tracing-service-name="}
+
+	config, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.LogLevels != "" {
+		t.Errorf("LOG_LEVELS = %q, want it cleared by the environment", config.LogLevels)
+	}
+	if config.TracingServiceName != "go-server-base" {
+		t.Errorf("TRACING_SERVICE_NAME = %q, want the default once the flag cleared it", config.TracingServiceName)
+	}
+	if !config.APIDocsEnabled {
+		t.Error("API_DOCS_ENABLED should fall back to its derived default once cleared")
+	}
+}
+
+func TestConfigLoaderClearedRequiredKey(t *testing.T) {
+	loader := testLoader(t, nil)
+	t.Setenv("DB_HOST", "")
+
+	if _, err := loader.Load(); err == nil || !strings.Contains(err.Error(), "DB_HOST is required") {
+		t.Errorf("Load() = %v, want DB_HOST to be required", err)
+	}
+}
+
+func TestConfigLoaderOptionalWithValidate(t *testing.T) {
+	tests := []struct {
+		value   string
+		set     bool
+		wantErr bool
+	}{
+		{set: false},
+		{value: "", set: true},
+		{value: "json", set: true},
+		{value: "xml", set: true, wantErr: true},
+	}
+
+	for _, test := range tests {
+		dotenv := map[string]string{}
+		if test.set {
+			dotenv["LOG_FORMAT"] = test.value
+		}
+
+		config, err := testLoader(t, dotenv).Load()
+		if test.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "LOG_FORMAT (from dotenv): expected one of json, text") {
+				t.Errorf("LOG_FORMAT=%q: Load() = %v, want a oneof error", test.value, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("LOG_FORMAT=%q: Load() = %v", test.value, err)
+			continue
+		}
+		if config.LogFormat != test.value {
+			t.Errorf("LOG_FORMAT = %q, want %q", config.LogFormat, test.value)
+		}
+	}
+}
+
+func TestConfigLoaderDurationBounds(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr string
+	}{
+		{value: "1s", want: time.Second},
+		{value: "2m", want: 2 * time.Minute},
+		{value: "999ms", wantErr: "must be at least 1s"},
+		{value: "soon", wantErr: "expected a duration"},
+	}
+
+	for _, test := range tests {
+		config, err := testLoader(t, map[string]string{"SHUTDOWN_TIMEOUT": test.value}).Load()
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("SHUTDOWN_TIMEOUT=%s: Load() = %v, want %q", test.value, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SHUTDOWN_TIMEOUT=%s: Load() = %v", test.value, err)
+			continue
+		}
+		if config.ShutdownTimeout != test.want {
+			t.Errorf("SHUTDOWN_TIMEOUT = %s, want %s", config.ShutdownTimeout, test.want)
+		}
+	}
+}
+
+func TestConfigLoaderReportsEveryError(t *testing.T) {
+	loader := testLoader(t, map[string]string{
+		"PORT":             "not-a-port",
+		"SHUTDOWN_TIMEOUT": "0s",
+		"DB_HOST":          "",
+	})
+	loader.Args = []string{"-access-token-max-age", "hunter2"}
+
+	_, err := loader.Load()
+	if err == nil {
+		t.Fatal("Load() succeeded with an invalid config")
+	}
+
+	for _, want := range []string{
+		"DB_HOST is required",
+		"PORT (from dotenv): expected a port",
+		"SHUTDOWN_TIMEOUT (from dotenv): must be at least 1s",
+		"ACCESS_TOKEN_MAX_AGE (from flag): expected an integer",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error is missing %q:\n%v", want, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+)
+
+var (
+	reloadMutex      sync.Mutex
+	subscribersMutex sync.Mutex
+	subscribers      []func(previous, current Config)
+)
+
+// ErrConfigWatch is passed to WatchConfig's onReload when the config files
+// can't be watched. SIGHUP still reloads.
+var ErrConfigWatch = errors.New("config files are no longer watched")
+
+type ReloadResult struct {
+	// Reloadable keys whose values were applied
+	Changed []string
+	// Keys that changed in the sources but need a restart to take effect
+	Ignored []string
+}
+
+// SubscribeConfig registers fn to run after every reload that changed a
+// value. Subscribers compare previous and current for the keys they own.
+func SubscribeConfig(fn func(previous, current Config)) {
+	subscribersMutex.Lock()
+	defer subscribersMutex.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// ReloadConfig re-reads every source and, when the result is valid, swaps in
+// the fields tagged reload. An invalid config leaves the current one in place.
+func ReloadConfig(loader ConfigLoader) (ReloadResult, error) {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	var result ReloadResult
+
+	loaded, err := loader.Load()
+	if err != nil {
+		return result, err
+	}
+
+	previous := GetConfig()
+	next := previous
+
+	previousValue := reflect.ValueOf(previous)
+	loadedValue := reflect.ValueOf(loaded)
+	nextValue := reflect.ValueOf(&next).Elem()
+
+	for _, field := range configFields() {
+		if reflect.DeepEqual(previousValue.Field(field.index).Interface(), loadedValue.Field(field.index).Interface()) {
+			continue
+		}
+
+		if !field.reloadable {
+			result.Ignored = append(result.Ignored, field.key)
+			continue
+		}
+
+		nextValue.Field(field.index).Set(loadedValue.Field(field.index))
+		result.Changed = append(result.Changed, field.key)
+	}
+
+	if len(result.Changed) == 0 {
+		return result, nil
+	}
+
+	SetConfig(next)
+
+	subscribersMutex.Lock()
+	notify := append([]func(Config, Config){}, subscribers...)
+	subscribersMutex.Unlock()
+
+	for _, fn := range notify {
+		fn(previous, next)
+	}
+
+	return result, nil
+}
+
+// WatchConfig reloads on SIGHUP and whenever one of loader's files changes,
+// reporting each attempt to onReload. When the files can't be watched it
+// reports an error wrapping ErrConfigWatch and carries on with SIGHUP alone.
+// It returns when ctx is done.
+func WatchConfig(ctx context.Context, loader ConfigLoader, onReload func(ReloadResult, error)) error {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- WatchFiles(ctx, loader.WatchedFiles(), func() {
+			onReload(ReloadConfig(loader))
+		}, func(err error) {
+			onReload(ReloadResult{}, err)
+		})
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if watchErr == nil {
+				return nil
+			}
+			return <-watchErr
+		case err := <-watchErr:
+			// A nil channel never receives, so the loop only waits on SIGHUP
+			watchErr = nil
+			if err != nil {
+				onReload(ReloadResult{}, fmt.Errorf("%w: %w", ErrConfigWatch, err))
+			}
+		case <-hangup:
+			onReload(ReloadConfig(loader))
+		}
+	}
+}
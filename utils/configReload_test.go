@@ -0,0 +1,58 @@
+//go:build unix
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWatchConfigKeepsSIGHUPWhenWatchingFails(t *testing.T) {
+	isolateEnv(t)
+
+	// The directory doesn't exist, so it can't be watched
+	loader := ConfigLoader{Path: filepath.Join(t.TempDir(), "missing") + string(filepath.Separator)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloads := make(chan error, 2)
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchConfig(ctx, loader, func(_ ReloadResult, err error) {
+			reloads <- err
+		})
+	}()
+
+	select {
+	case err := <-reloads:
+		if !errors.Is(err, ErrConfigWatch) {
+			t.Fatalf("first report = %v, want ErrConfigWatch", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch failure was not reported")
+	}
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-reloads:
+		// The loader has no required keys, so the reload itself is rejected
+		if err == nil || errors.Is(err, ErrConfigWatch) {
+			t.Fatalf("SIGHUP report = %v, want a reload error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGHUP did not reload after the watcher failed")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("WatchConfig() = %v, want nil", err)
+	}
+}
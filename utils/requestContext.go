@@ -0,0 +1,44 @@
+package utils
+
+import "context"
+
+type contextKey string
+
+const (
+	requestIDKey contextKey = "requestId"
+	userIDKey    contextKey = "userId"
+	routeKey     contextKey = "route"
+)
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func GetRequestID(ctx context.Context) string {
+	return contextString(ctx, requestIDKey)
+}
+
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+func GetUserID(ctx context.Context) string {
+	return contextString(ctx, userIDKey)
+}
+
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey, route)
+}
+
+func GetRoute(ctx context.Context) string {
+	return contextString(ctx, routeKey)
+}
+
+func contextString(ctx context.Context, key contextKey) string {
+	if ctx == nil {
+		return ""
+	}
+
+	value, _ := ctx.Value(key).(string)
+	return value
+}
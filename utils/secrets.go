@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// SecretProvider looks up config values held outside the environment, such
+// as a secrets manager or files mounted by the orchestrator
+type SecretProvider interface {
+	// Secret returns the value for key, ok is false when the provider has none
+	Secret(key string) (value string, ok bool, err error)
+}
+
+// FileSecretProvider reads each key from a file of the same name in Dir,
+// e.g. /run/secrets/DB_PASSWORD or /run/secrets/db_password
+type FileSecretProvider struct {
+	Dir string
+}
+
+func (p FileSecretProvider) Secret(key string) (string, bool, error) {
+	for _, name := range []string{key, strings.ToLower(key)} {
+		value, err := readSecretFile(filepath.Join(p.Dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", false, err
+		}
+		return value, true, nil
+	}
+	return "", false, nil
+}
+
+// readSecretFile drops the trailing newline most tools write after a secret
+func readSecretFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// Redacted returns the config keyed by mapstructure name with secret values
+// replaced, safe to print or log
+func (c Config) Redacted() map[string]any {
+	value := reflect.ValueOf(c)
+
+	fields := configFields()
+	values := make(map[string]any, len(fields))
+	for _, field := range fields {
+		fieldValue := value.Field(field.index)
+		if field.secret && !fieldValue.IsZero() {
+			values[field.key] = redacted
+			continue
+		}
+		values[field.key] = fieldValue.Interface()
+	}
+	return values
+}
+
+func (c Config) String() string {
+	return fmt.Sprint(c.Redacted())
+}
+
+func (c Config) GoString() string {
+	return c.String()
+}
+
+func (c Config) LogValue() slog.Value {
+	redactedValues := c.Redacted()
+
+	attrs := make([]slog.Attr, 0, len(redactedValues))
+	for _, field := range configFields() {
+		attrs = append(attrs, slog.Any(field.key, redactedValues[field.key]))
+	}
+	return slog.GroupValue(attrs...)
+}
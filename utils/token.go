@@ -4,7 +4,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -79,7 +79,16 @@ func ValidateToken(token string) (uuid.UUID, error) {
 		return uuid.Nil, fmt.Errorf("validate: invalid token")
 	}
 
-	sub := claims["sub"].(uuid.UUID)
+	// Claims are decoded from JSON, so the subject arrives as a string
+	subject, ok := claims["sub"].(string)
+	if !ok {
+		return uuid.Nil, fmt.Errorf("validate: invalid subject")
+	}
+
+	sub, err := uuid.Parse(subject)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("validate: invalid subject: %w", err)
+	}
 
 	return sub, nil
 }
@@ -87,26 +96,26 @@ func ValidateToken(token string) (uuid.UUID, error) {
 func DaysTokenValid(token string) int {
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
-		log.Println("Invalid token format")
+		slog.Warn("Invalid token format")
 		return 0
 	}
 
 	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
-		log.Printf("Error decoding token payload: %v", err)
+		slog.Warn("Error decoding token payload", "error", err)
 		return 0
 	}
 
 	var claims map[string]interface{}
 	err = json.Unmarshal(payload, &claims)
 	if err != nil {
-		log.Printf("Error unmarshalling token payload: %v", err)
+		slog.Warn("Error unmarshalling token payload", "error", err)
 		return 0
 	}
 
 	exp, ok := claims["exp"].(float64)
 	if !ok {
-		log.Println("Error: exp claim is not a float64")
+		slog.Warn("Token exp claim is not a float64")
 		return 0
 	}
 
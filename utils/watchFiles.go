@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Changes are collected for this long so an editor's write and rename
+// trigger a single callback
+const watchDebounce = 500 * time.Millisecond
+
+// WatchFiles calls onChange after any of files is written, created or
+// replaced, until ctx is done. Watcher errors go to onError.
+func WatchFiles(ctx context.Context, files []string, onChange func(), onError func(error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// Directories are watched rather than the files, since editors and
+	// mounted secrets or config maps replace files instead of writing to them
+	watched := map[string]bool{}
+	directories := map[string]bool{}
+	for _, file := range files {
+		absolute, err := filepath.Abs(file)
+		if err != nil {
+			continue
+		}
+		watched[absolute] = true
+
+		directory := filepath.Dir(absolute)
+		if directories[directory] {
+			continue
+		}
+		if err := watcher.Add(directory); err != nil {
+			return err
+		}
+		directories[directory] = true
+	}
+
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			absolute, err := filepath.Abs(event.Name)
+			if err != nil {
+				continue
+			}
+			// Kubernetes swaps mounted files by retargeting a ..data symlink
+			if watched[absolute] || strings.HasPrefix(filepath.Base(absolute), "..") {
+				debounce.Reset(watchDebounce)
+			}
+		case <-debounce.C:
+			onChange()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			onError(err)
+		}
+	}
+}
@@ -1,20 +1,28 @@
 package websockets
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bparsons094/go-server-base/database"
+	"github.com/bparsons094/go-server-base/logging"
+	"github.com/bparsons094/go-server-base/metrics"
 	"github.com/bparsons094/go-server-base/models"
+	"github.com/bparsons094/go-server-base/tracing"
 	"github.com/bparsons094/go-server-base/utils"
 	"github.com/gofiber/websocket/v2"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var logger = logging.For("websocket")
+
 type UserConnections struct {
 	Connections []*websocket.Conn
 }
@@ -23,13 +31,21 @@ type WebSocketService struct {
 	ConnectedUsers         sync.Map
 	ConnectionLastResponse sync.Map
 	ReceiveNotify          chan interface{}
+	// Per connection *sync.Mutex. Handlers, broadcasts, the keep-alive loop
+	// and Shutdown all write, and a connection allows one writer at a time.
+	writeLocks    sync.Map
+	lastKeepAlive atomic.Int64
+	stopKeepAlive chan struct{}
 }
 
+const keepAliveInterval = 15 * time.Second
+
 type WebSocketMessage struct {
-	Type       string       `json:"type"`
-	Payload    interface{}  `json:"payload"`
-	Authorized bool         `json:"authorized"`
-	User       *models.User `json:"user"`
+	Type          string       `json:"type"`
+	Payload       interface{}  `json:"payload"`
+	Authorized    bool         `json:"authorized"`
+	User          *models.User `json:"user"`
+	CorrelationID string       `json:"correlationId,omitempty"`
 }
 
 func NewWebSocketService() *WebSocketService {
@@ -37,7 +53,10 @@ func NewWebSocketService() *WebSocketService {
 	service := &WebSocketService{
 		ConnectedUsers: sync.Map{},
 		ReceiveNotify:  receiveChannel,
+		stopKeepAlive:  make(chan struct{}),
 	}
+	// Counts as a run so the hub isn't reported down before the first tick
+	service.lastKeepAlive.Store(time.Now().UnixNano())
 
 	go service.startKeepAlive()
 
@@ -45,18 +64,25 @@ func NewWebSocketService() *WebSocketService {
 }
 
 func (s *WebSocketService) HandleWebSocketConnection(c *websocket.Conn) {
+	// The upgrade request's ID identifies the connection itself
+	connectionID := connectionRequestID(c)
+
+	s.writeLocks.Store(c, &sync.Mutex{})
+	defer s.writeLocks.Delete(c)
+
 	user, err := s.AuthUser(c)
 	if err != nil {
 		s.sendMessage(c, WebSocketMessage{
-			Type:       "connection",
-			Payload:    err.Error(),
-			Authorized: false,
+			Type:          "connection",
+			Payload:       err.Error(),
+			Authorized:    false,
+			CorrelationID: connectionID,
 		})
 		c.Close()
 		return
 	}
 
-	s.onConnect(c, user)
+	s.onConnect(c, user, connectionID)
 	defer s.onDisconnect(c, user)
 
 	// Handle incoming WebSocket connections here
@@ -64,22 +90,31 @@ func (s *WebSocketService) HandleWebSocketConnection(c *websocket.Conn) {
 		messageType, msg, err := c.ReadMessage()
 
 		if messageType != websocket.TextMessage {
-			log.Println("Message Type is not TextMessage", messageType)
+			logger.Warn("Message type is not TextMessage", "message_type", messageType, "connection_id", connectionID)
 			break
 		}
 
 		if err != nil {
-			log.Println("Read Message Error: ", err)
+			logger.Debug("Read message error", "error", err, "connection_id", connectionID)
 			break
 		}
 
 		var message WebSocketMessage
 		err = json.Unmarshal(msg, &message)
 		if err != nil {
-			log.Println("Error Unmarshalling Message: ", err)
+			logger.Warn("Error unmarshalling message", "error", err, "connection_id", connectionID)
 			continue
 		}
 
+		// Clients may supply their own correlation ID to tie a UI action to the server side work
+		if message.CorrelationID == "" {
+			message.CorrelationID = uuid.NewString()
+		}
+
+		metrics.WebSocketMessages.WithLabelValues("in", messageTypeLabel(message.Type)).Inc()
+
+		ctx, span := startMessageSpan(c, connectionID, message)
+
 		// Handle Different Message Types
 		switch message.Type {
 		case "connection":
@@ -88,10 +123,51 @@ func (s *WebSocketService) HandleWebSocketConnection(c *websocket.Conn) {
 			// s.handleMessage(message, c)
 		case "pong":
 			s.ConnectionLastResponse.Store(c, time.Now().UTC())
+		default:
+			logger.WarnContext(ctx, "Unknown message type", "type", message.Type, "correlation_id", message.CorrelationID, "connection_id", connectionID)
 		}
+
+		span.End()
 	}
 }
 
+// Each message gets its own trace, linked to the span of the upgrade request
+// rather than nested under it, since that span lives as long as the connection
+func startMessageSpan(c *websocket.Conn, connectionID string, message WebSocketMessage) (context.Context, trace.Span) {
+	options := []trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("websocket.message_type", message.Type),
+			attribute.String("websocket.correlation_id", message.CorrelationID),
+			attribute.String("websocket.connection_id", connectionID),
+		),
+	}
+	if spanContext, ok := c.Locals("spanContext").(trace.SpanContext); ok && spanContext.IsValid() {
+		options = append(options, trace.WithLinks(trace.Link{SpanContext: spanContext}))
+	}
+
+	ctx := utils.WithRequestID(context.Background(), connectionID)
+	return tracing.Tracer().Start(ctx, "websocket "+messageTypeLabel(message.Type), options...)
+}
+
+// Message types come from clients, so unknown ones share a label to bound cardinality
+func messageTypeLabel(messageType string) string {
+	switch messageType {
+	case "connection", "message", "ping", "pong":
+		return messageType
+	}
+	return "unknown"
+}
+
+func connectionRequestID(c *websocket.Conn) string {
+	if requestID, ok := c.Locals("requestId").(string); ok && requestID != "" {
+		return requestID
+	}
+
+	return uuid.NewString()
+}
+
 func (s *WebSocketService) AuthUser(c *websocket.Conn) (models.User, error) {
 	token := c.Query("token")
 
@@ -104,40 +180,48 @@ func (s *WebSocketService) AuthUser(c *websocket.Conn) (models.User, error) {
 		return models.User{}, err
 	}
 
+	ctx := context.Background()
+	if requestID, ok := c.Locals("requestId").(string); ok {
+		ctx = utils.WithRequestID(ctx, requestID)
+	}
+
 	var user models.User
 	DB := database.GetDatabase()
-	if err := DB.Where("id = ?", sub).First(&user).Error; err != nil {
+	if err := DB.WithContext(ctx).Where("id = ?", sub).First(&user).Error; err != nil {
 		return models.User{}, errors.New("User not found")
 	}
 
 	return user, nil
 }
 
-func (s *WebSocketService) onConnect(c *websocket.Conn, user models.User) {
+func (s *WebSocketService) onConnect(c *websocket.Conn, user models.User, connectionID string) {
 
 	userConnInterface, _ := s.ConnectedUsers.LoadOrStore(user.ID, &UserConnections{})
 
 	userConnections := userConnInterface.(*UserConnections)
 	userConnections.Connections = append(userConnections.Connections, c)
+	metrics.WebSocketConnections.Inc()
 
 	// Store the connection's last response time
 	s.ConnectionLastResponse.Store(c, time.Now().UTC())
 
 	// Send welcome message to the connected user
 	welcomeMessage := WebSocketMessage{
-		Type:       "connection",
-		Payload:    "Welcome to The WebSocket!",
-		Authorized: true,
-		User:       &user,
+		Type:          "connection",
+		Payload:       "Welcome to The WebSocket!",
+		Authorized:    true,
+		User:          &user,
+		CorrelationID: connectionID,
 	}
 	s.sendMessage(c, welcomeMessage)
 
 	// Broadcast new user connection message
 	broadcastMessage := WebSocketMessage{
-		Type:       "connection",
-		Payload:    "A new user has connected!",
-		Authorized: true,
-		User:       &user,
+		Type:          "connection",
+		Payload:       "A new user has connected!",
+		Authorized:    true,
+		User:          &user,
+		CorrelationID: connectionID,
 	}
 	s.broadcast(broadcastMessage)
 }
@@ -155,19 +239,31 @@ func (s *WebSocketService) broadcast(data WebSocketMessage) {
 func (s *WebSocketService) sendMessage(c *websocket.Conn, data WebSocketMessage) {
 	msg, err := json.Marshal(data)
 	if err != nil {
-		log.Println("Error Marshalling Message: ", err)
+		logger.Error("Error marshalling message", "error", err, "correlation_id", data.CorrelationID)
 		return
 	}
 
-	if err := c.WriteMessage(websocket.TextMessage, msg); err != nil {
-		log.Println("Error Sending Message: ", err)
+	lock, ok := s.writeLocks.Load(c)
+	if !ok {
+		// The connection's handler has returned, so it is already closed
+		return
 	}
+	lock.(*sync.Mutex).Lock()
+	err = c.WriteMessage(websocket.TextMessage, msg)
+	lock.(*sync.Mutex).Unlock()
+
+	if err != nil {
+		logger.Warn("Error sending message", "error", err, "correlation_id", data.CorrelationID)
+		return
+	}
+
+	metrics.WebSocketMessages.WithLabelValues("out", messageTypeLabel(data.Type)).Inc()
 }
 
 func (s *WebSocketService) onDisconnect(c *websocket.Conn, user models.User) {
 	userConnInterface, ok := s.ConnectedUsers.Load(user.ID)
 	if !ok {
-		log.Printf("No connections found for user: %v", user.ID)
+		logger.Warn("No connections found for user", "user_id", user.ID)
 		return
 	}
 
@@ -182,58 +278,99 @@ func (s *WebSocketService) onDisconnect(c *websocket.Conn, user models.User) {
 		if conn == c {
 			userConnections.Connections[i] = userConnections.Connections[len(userConnections.Connections)-1]
 			userConnections.Connections = userConnections.Connections[:len(userConnections.Connections)-1]
+			metrics.WebSocketConnections.Dec()
 			break
 		}
 	}
 
 	err := c.Close()
 	if err != nil {
-		log.Printf("Error closing connection for user %v: %v", user.ID, err)
+		logger.Warn("Error closing connection", "user_id", user.ID, "error", err)
 	}
 }
 
 func (s *WebSocketService) startKeepAlive() {
-	ticker := time.NewTicker(15 * time.Second)
+	ticker := time.NewTicker(keepAliveInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
 			s.handleKeepAlive()
+			s.lastKeepAlive.Store(time.Now().UnixNano())
+		case <-s.stopKeepAlive:
+			return
+		}
+	}
+}
+
+// Shutdown stops the keep-alive loop and asks every client to reconnect,
+// which lands them on another instance while this one drains
+func (s *WebSocketService) Shutdown(ctx context.Context) error {
+	close(s.stopKeepAlive)
+
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	s.ConnectedUsers.Range(func(key, value interface{}) bool {
+		userConns := value.(*UserConnections)
+
+		connections := append([]*websocket.Conn(nil), userConns.Connections...)
+		for _, conn := range connections {
+			if ctx.Err() != nil {
+				return false
+			}
+
+			s.sendMessage(conn, WebSocketMessage{
+				Type:       "reconnect",
+				Payload:    "Server is shutting down, please reconnect",
+				Authorized: true,
+			})
+			if err := conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second)); err != nil {
+				logger.Debug("Error sending close message", "user_id", key, "error", err)
+			}
+			s.onDisconnect(conn, models.User{ID: key.(uuid.UUID)})
 		}
+		return true
+	})
+
+	return ctx.Err()
+}
+
+// HealthCheck reports the hub as down when the keep-alive loop has stalled
+func (s *WebSocketService) HealthCheck(ctx context.Context) error {
+	lastKeepAlive := time.Unix(0, s.lastKeepAlive.Load())
+	if since := time.Since(lastKeepAlive); since > 2*keepAliveInterval {
+		return fmt.Errorf("keep-alive loop has not run for %s", since.Round(time.Second))
 	}
+	return nil
 }
 
 func (s *WebSocketService) handleKeepAlive() {
 	// Loop through all connected users and send a ping message to each connection every 15 seconds.
 	s.ConnectedUsers.Range(func(key, value interface{}) bool {
-		userConnections := value.(*sync.Map)
-		userConnections.Range(func(subKey, subValue interface{}) bool {
-			userConns := subValue.(*UserConnections)
-			for _, conn := range userConns.Connections {
-
-				lastResponseTime, ok := s.ConnectionLastResponse.Load(conn)
-				if !ok {
-					log.Println("Error getting last response time for connection")
-					continue
-				}
-
-				if time.Since(lastResponseTime.(time.Time)) > time.Minute {
-					log.Println("Connection has not responded in over a minute, closing connection for user", subKey)
-					s.onDisconnect(conn, models.User{ID: subKey.(uuid.UUID)})
-					continue
-				}
-
-				connMemoryAddress := fmt.Sprintf("%p", conn)
-				s.sendMessage(conn, WebSocketMessage{
-					Type:       "ping",
-					Payload:    connMemoryAddress,
-					Authorized: true,
-				})
+		userConns := value.(*UserConnections)
+
+		// onDisconnect mutates the slice, so iterate over a copy
+		connections := append([]*websocket.Conn(nil), userConns.Connections...)
+		for _, conn := range connections {
+			lastResponseTime, ok := s.ConnectionLastResponse.Load(conn)
+			if !ok {
+				logger.Warn("Error getting last response time for connection")
+				continue
 			}
 
-			return true
-		})
+			if time.Since(lastResponseTime.(time.Time)) > time.Minute {
+				logger.Info("Connection has not responded in over a minute, closing", "user_id", key)
+				s.onDisconnect(conn, models.User{ID: key.(uuid.UUID)})
+				continue
+			}
+
+			connMemoryAddress := fmt.Sprintf("%p", conn)
+			s.sendMessage(conn, WebSocketMessage{
+				Type:       "ping",
+				Payload:    connMemoryAddress,
+				Authorized: true,
+			})
+		}
 
 		return true
 	})